@@ -0,0 +1,153 @@
+package slogecho
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// BinaryBodyFormat is the representation of the captured bodies that are not
+// logged as text.
+type BinaryBodyFormat string
+
+const (
+	// BinaryBodyHash logs the size and the sha256 hash of the body.
+	BinaryBodyHash BinaryBodyFormat = "hash"
+	// BinaryBodyBase64 logs the size and the base64 encoded prefix of the body.
+	BinaryBodyBase64 BinaryBodyFormat = "base64"
+	// BinaryBodyHex logs the size and the hex encoded prefix of the body.
+	BinaryBodyHex BinaryBodyFormat = "hex"
+)
+
+// bodyAttributes returns the captured body, or the reference of the spooled body.
+// The body attribute is always a string: binary and spooled bodies are logged
+// under the body_binary and body_spool groups instead.
+func (config Config) bodyAttributes(body *bytes.Buffer, s *spool, header http.Header, maxSize int) []slog.Attr {
+	if s != nil && s.err == nil {
+		return []slog.Attr{slog.Group("body_spool", s.attrs()...)}
+	}
+
+	var captured []byte
+	if body != nil {
+		captured = body.Bytes()
+	}
+	encoding := contentEncoding(header)
+	attributes := []slog.Attr{}
+
+	if config.WithDecompression && encoding != "" && len(captured) > 0 {
+		decoded, err := decompressBody(config.Decompressors, encoding, captured, maxSize)
+		if err != nil {
+			attributes = append(attributes, slog.String("decompression_error", err.Error()))
+		} else {
+			attributes = append(attributes, decompressionAttributes(encoding, captured, decoded))
+			captured = decoded
+			encoding = ""
+		}
+	}
+
+	attributes = append([]slog.Attr{config.bodyAttr(captured, header.Get("Content-Type"), encoding != "")}, attributes...)
+
+	if s != nil {
+		attributes = append(attributes, slog.String("spool_error", s.err.Error()))
+	}
+
+	return attributes
+}
+
+// contentEncoding returns the Content-Encoding of a body, or an empty string
+// when not encoded.
+func contentEncoding(header http.Header) string {
+	encoding := header.Get("Content-Encoding")
+	if strings.EqualFold(encoding, "identity") {
+		return ""
+	}
+	return encoding
+}
+
+// bodyAttr applies the content-type policy to a captured body.
+func (config Config) bodyAttr(body []byte, contentType string, encoded bool) slog.Attr {
+	if config.BinaryBodyFormat == "" && len(config.BodyContentTypes) == 0 && len(config.BodyExcludedContentTypes) == 0 {
+		return slog.String("body", string(body))
+	}
+
+	if config.isTextBody(body, contentType, encoded) {
+		return slog.String("body", string(body))
+	}
+
+	format := config.BinaryBodyFormat
+	if format != BinaryBodyBase64 && format != BinaryBodyHex {
+		format = BinaryBodyHash
+	}
+
+	return binaryBodyAttr(format, body)
+}
+
+func (config Config) isTextBody(body []byte, contentType string, encoded bool) bool {
+	// compressed payloads
+	if encoded {
+		return false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if len(config.BodyContentTypes) > 0 && !matchMediaType(mediaType, config.BodyContentTypes) {
+		return false
+	}
+	if matchMediaType(mediaType, config.BodyExcludedContentTypes) {
+		return false
+	}
+
+	return config.BinaryBodyFormat == "" || isText(body)
+}
+
+// matchMediaType returns true when the media type matches one of the patterns
+// (eg: "application/json", "image/*", "application/*+json").
+func matchMediaType(mediaType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), mediaType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isText sniffs the body: valid UTF-8 (the capture may end in the middle of
+// a rune) and a text content type.
+func isText(body []byte) bool {
+	valid := body
+	for i := 0; i < utf8.UTFMax && len(valid) > 0 && !utf8.Valid(valid); i++ {
+		valid = valid[:len(valid)-1]
+	}
+	if !utf8.Valid(valid) {
+		return false
+	}
+
+	return strings.HasPrefix(http.DetectContentType(body), "text/")
+}
+
+func binaryBodyAttr(format BinaryBodyFormat, body []byte) slog.Attr {
+	attributes := []any{
+		slog.String("representation", string(format)),
+		slog.Int("size", len(body)),
+	}
+
+	preview := body[:min(len(body), BinaryBodyPreviewSize)]
+
+	switch format {
+	case BinaryBodyBase64:
+		attributes = append(attributes, slog.String("data", base64.StdEncoding.EncodeToString(preview)))
+	case BinaryBodyHex:
+		attributes = append(attributes, slog.String("data", hex.EncodeToString(preview)))
+	case BinaryBodyHash:
+		sum := sha256.Sum256(body)
+		attributes = append(attributes, slog.String("sha256", hex.EncodeToString(sum[:])))
+	}
+
+	return slog.Group("body_binary", attributes...)
+}
@@ -0,0 +1,67 @@
+package slogecho
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+)
+
+func TestBodyAttributeType(t *testing.T) {
+	// sniffed as binary, but valid UTF-8: JSON keeps the body as is
+	blob := "\x00\x01\x02\x03rest"
+
+	tests := []struct {
+		name           string
+		format         BinaryBodyFormat
+		contentType    string
+		body           string
+		representation string
+	}{
+		{name: "text", format: BinaryBodyHash, contentType: "text/plain", body: "hello world"},
+		{name: "json", format: BinaryBodyHash, contentType: "application/json", body: `{"hello":"world"}`},
+		{name: "binary", format: BinaryBodyHash, contentType: "image/png", body: blob, representation: "hash"},
+		{name: "binary without policy", contentType: "image/png", body: blob},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf syncBuffer
+
+			config := DefaultConfig()
+			config.WithResponseBody = true
+			config.BinaryBodyFormat = tt.format
+
+			e := echo.New()
+			e.Use(NewWithConfig(slog.New(slog.NewJSONHandler(&buf, nil)), config))
+			e.GET("/", func(c *echo.Context) error {
+				return c.Blob(200, tt.contentType, []byte(tt.body))
+			})
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			var record struct {
+				Response map[string]any `json:"response"`
+			}
+			if err := json.Unmarshal([]byte(buf.String()), &record); err != nil {
+				t.Fatal(err)
+			}
+
+			if body, ok := record.Response["body"]; ok {
+				if _, ok := body.(string); !ok {
+					t.Errorf("expected body to be a string, got: %T", body)
+				}
+			}
+
+			binary, _ := record.Response["body_binary"].(map[string]any)
+			if tt.representation == "" {
+				if record.Response["body"] != tt.body || binary != nil {
+					t.Errorf("expected a text body, got: %v", record.Response)
+				}
+			} else if binary["representation"] != tt.representation {
+				t.Errorf("expected a %s representation, got: %v", tt.representation, record.Response)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package slogecho
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+var errDecompressionRatio = errors.New("decompression ratio exceeded")
+
+// Decompressor returns a reader decoding a body with a given Content-Encoding.
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+// DefaultDecompressors returns the decoders of the gzip, deflate, br and zstd
+// content encodings. The map can be extended or narrowed, and set in
+// Config.Decompressors.
+func DefaultDecompressors() map[string]Decompressor {
+	return map[string]Decompressor{
+		"gzip": func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		"x-gzip": func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		"deflate": newDeflateReader,
+		"br": func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(brotli.NewReader(r)), nil
+		},
+		"zstd": func(r io.Reader) (io.ReadCloser, error) {
+			d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		},
+	}
+}
+
+// newDeflateReader reads the zlib format (RFC 9110), or raw deflate, sent by
+// some servers.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+
+	header, _ := br.Peek(2)
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+
+	return flate.NewReader(br), nil
+}
+
+// decompressBody decodes a captured body, up to maxSize bytes. The capture may
+// be truncated, so the decoded body is truncated too.
+func decompressBody(decompressors map[string]Decompressor, encoding string, body []byte, maxSize int) ([]byte, error) {
+	if decompressors == nil {
+		decompressors = DefaultDecompressors()
+	}
+
+	decompressor, ok := decompressors[strings.ToLower(strings.TrimSpace(encoding))]
+	if !ok {
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	r, err := decompressor(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// guard against decompression bombs
+	limit := min(maxSize, len(body)*DecompressionMaxRatio)
+
+	decoded, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if len(decoded) > limit {
+		if limit < maxSize {
+			return nil, errDecompressionRatio
+		}
+		return decoded[:limit], nil
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) && len(decoded) > 0 {
+		// truncated capture
+		return decoded, nil
+	}
+
+	return decoded, err
+}
+
+// decompressionAttributes returns the captured and decoded sizes of a body.
+// The captured size is the size of the encoded bytes captured, which may be
+// truncated to the capture limit (see request.length and response.length for
+// the full size).
+func decompressionAttributes(encoding string, captured []byte, decoded []byte) slog.Attr {
+	return slog.Group(
+		"decompression",
+		slog.String("encoding", encoding),
+		slog.Int("captured_size", len(captured)),
+		slog.Int("decoded_size", len(decoded)),
+	)
+}
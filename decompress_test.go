@@ -0,0 +1,157 @@
+package slogecho
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v5"
+)
+
+func compress(t *testing.T, newWriter func(w io.Writer) io.WriteCloser, body string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompressBody(t *testing.T) {
+	const body = `{"hello":"world"}`
+
+	upper := map[string]Decompressor{
+		"x-upper": func(r io.Reader) (io.ReadCloser, error) {
+			b, err := io.ReadAll(r)
+			return io.NopCloser(strings.NewReader(strings.ToUpper(string(b)))), err
+		},
+	}
+
+	tests := []struct {
+		name          string
+		decompressors map[string]Decompressor
+		encoding      string
+		encoded       []byte
+		expect        string
+		err           bool
+	}{
+		{
+			name:     "gzip",
+			encoding: "gzip",
+			encoded:  compress(t, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }, body),
+			expect:   body,
+		},
+		{
+			name:     "zlib deflate",
+			encoding: "Deflate",
+			encoded:  compress(t, func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }, body),
+			expect:   body,
+		},
+		{
+			name:     "raw deflate",
+			encoding: "deflate",
+			encoded: compress(t, func(w io.Writer) io.WriteCloser {
+				fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+				return fw
+			}, body),
+			expect: body,
+		},
+		{
+			name:     "br",
+			encoding: "br",
+			encoded:  compress(t, func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }, body),
+			expect:   body,
+		},
+		{
+			name:     "zstd",
+			encoding: "zstd",
+			encoded: compress(t, func(w io.Writer) io.WriteCloser {
+				zw, _ := zstd.NewWriter(w)
+				return zw
+			}, body),
+			expect: body,
+		},
+		{
+			name:     "unsupported",
+			encoding: "x-unknown",
+			encoded:  []byte(body),
+			err:      true,
+		},
+		{
+			name:          "plugged",
+			decompressors: upper,
+			encoding:      "x-upper",
+			encoded:       []byte(body),
+			expect:        strings.ToUpper(body),
+		},
+		{
+			name:          "not plugged",
+			decompressors: upper,
+			encoding:      "gzip",
+			encoded:       compress(t, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }, body),
+			err:           true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := decompressBody(tt.decompressors, tt.encoding, tt.encoded, 1024)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected an error, got: %q", decoded)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(decoded) != tt.expect {
+				t.Errorf("expected %q, got: %q", tt.expect, decoded)
+			}
+		})
+	}
+}
+
+func TestDecompressionAttributes(t *testing.T) {
+	var buf syncBuffer
+
+	config := DefaultConfig()
+	config.WithResponseBody = true
+	config.WithDecompression = true
+
+	body := strings.Repeat("hello world ", 10)
+	encoded := compress(t, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }, body)
+
+	e := echo.New()
+	e.Use(NewWithConfig(slog.New(slog.NewTextHandler(&buf, nil)), config))
+	e.GET("/", func(c *echo.Context) error {
+		c.Response().Header().Set("Content-Encoding", "gzip")
+		return c.Blob(200, "text/plain", encoded)
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	output := buf.String()
+	for _, expect := range []string{
+		"response.decompression.encoding=gzip",
+		"response.decompression.captured_size=" + strconv.Itoa(len(encoded)),
+		"response.decompression.decoded_size=" + strconv.Itoa(len(body)),
+	} {
+		if !strings.Contains(output, expect) {
+			t.Errorf("expected %s, got: %s", expect, output)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package slogecho
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type dedupKey struct {
+	route       string
+	status      int
+	fingerprint string
+}
+
+type dedupEntry struct {
+	level slog.Level
+	msg   string
+	timer *time.Timer
+
+	count      int
+	suppressed int
+	first      time.Time
+	last       time.Time
+	latencyMin time.Duration
+	latencyMax time.Duration
+	latencySum time.Duration
+}
+
+// deduplicator suppresses repeated records within a time window and logs a
+// summary of the suppressed records when the window closes.
+type deduplicator struct {
+	logger *slog.Logger
+	window time.Duration
+	burst  int
+
+	mu      sync.Mutex
+	entries map[dedupKey]*dedupEntry
+}
+
+func newDeduplicator(logger *slog.Logger, window time.Duration, burst int) *deduplicator {
+	return &deduplicator{
+		logger:  logger,
+		window:  window,
+		burst:   max(burst, 1),
+		entries: map[dedupKey]*dedupEntry{},
+	}
+}
+
+// allow returns false when the record must be suppressed.
+func (d *deduplicator) allow(key dedupKey, level slog.Level, msg string, now time.Time, latency time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.entries[key]
+	if !ok {
+		entry = &dedupEntry{
+			level: level,
+			msg:   msg,
+			first: now,
+		}
+		entry.timer = time.AfterFunc(d.window, func() { d.flush(key, entry) })
+		d.entries[key] = entry
+	}
+
+	entry.count++
+	entry.last = now
+	if entry.count <= d.burst {
+		return true
+	}
+
+	if entry.suppressed == 0 || latency < entry.latencyMin {
+		entry.latencyMin = latency
+	}
+	if latency > entry.latencyMax {
+		entry.latencyMax = latency
+	}
+	entry.latencySum += latency
+	entry.suppressed++
+
+	return false
+}
+
+func (d *deduplicator) flush(key dedupKey, entry *dedupEntry) {
+	d.mu.Lock()
+	current, ok := d.entries[key]
+	if !ok || current != entry {
+		// already flushed by flushAll
+		d.mu.Unlock()
+		return
+	}
+	delete(d.entries, key)
+	d.mu.Unlock()
+
+	d.log(key, entry)
+}
+
+func (d *deduplicator) flushAll() {
+	d.mu.Lock()
+	entries := d.entries
+	d.entries = map[dedupKey]*dedupEntry{}
+	d.mu.Unlock()
+
+	for key, entry := range entries {
+		entry.timer.Stop()
+		d.log(key, entry)
+	}
+}
+
+func (d *deduplicator) log(key dedupKey, entry *dedupEntry) {
+	if entry.suppressed == 0 {
+		return
+	}
+
+	d.logger.LogAttrs(
+		context.Background(),
+		entry.level,
+		entry.msg,
+		slog.Group(
+			"request",
+			slog.String("route", key.route),
+		),
+		slog.Group(
+			"response",
+			slog.Int("status", key.status),
+			slog.Group(
+				"latency_stats",
+				slog.Duration("min", entry.latencyMin),
+				slog.Duration("max", entry.latencyMax),
+				slog.Duration("avg", entry.latencySum/time.Duration(entry.suppressed)),
+			),
+		),
+		slog.Group(
+			"error",
+			slog.String("fingerprint", key.fingerprint),
+		),
+		slog.Int("suppressed_count", entry.suppressed),
+		slog.Time("first", entry.first.UTC()),
+		slog.Time("last", entry.last.UTC()),
+	)
+}
@@ -0,0 +1,134 @@
+package slogecho
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v5"
+)
+
+func newDedupTestServer(buf *syncBuffer, window time.Duration, burst int) (*echo.Echo, *Middleware) {
+	config := DefaultConfig()
+	config.DedupWindow = window
+	config.DedupBurst = burst
+
+	m := NewMiddleware(slog.New(slog.NewTextHandler(buf, nil)), config)
+
+	e := echo.New()
+	e.Use(m.Handler)
+	e.GET("/users/:id", func(c *echo.Context) error {
+		return echo.NewHTTPError(500, "database is down")
+	})
+	e.GET("/missing", func(c *echo.Context) error {
+		return echo.ErrNotFound
+	})
+
+	return e, m
+}
+
+func serveTimes(e *echo.Echo, path string, times int) {
+	for range times {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+}
+
+func countRecords(output string) (access int, summaries int) {
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		switch {
+		case line == "":
+		case strings.Contains(line, "suppressed_count="):
+			summaries++
+		default:
+			access++
+		}
+	}
+	return access, summaries
+}
+
+func TestDedupWindowSummary(t *testing.T) {
+	var buf syncBuffer
+	e, _ := newDedupTestServer(&buf, 50*time.Millisecond, 2)
+
+	serveTimes(e, "/users/1", 5)
+	serveTimes(e, "/users/2", 1)
+
+	if access, summaries := countRecords(buf.String()); access != 2 || summaries != 0 {
+		t.Fatalf("expected 2 records within the burst and no summary, got %d and %d: %s", access, summaries, buf.String())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, summaries := countRecords(buf.String()); summaries > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected a summary when the window closes")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	output := buf.String()
+	for _, expect := range []string{
+		"request.route=/users/:id",
+		"response.status=500",
+		"suppressed_count=4",
+		"response.latency_stats.min=",
+		"response.latency_stats.max=",
+		"response.latency_stats.avg=",
+		"error.fingerprint=",
+	} {
+		if !strings.Contains(output, expect) {
+			t.Errorf("expected %s, got: %s", expect, output)
+		}
+	}
+
+	// a new window starts
+	serveTimes(e, "/users/3", 1)
+	if access, summaries := countRecords(buf.String()); access != 3 || summaries != 1 {
+		t.Errorf("expected 3 records and 1 summary, got %d and %d: %s", access, summaries, buf.String())
+	}
+}
+
+func TestDedupFlushOnShutdown(t *testing.T) {
+	var buf syncBuffer
+	e, m := newDedupTestServer(&buf, time.Hour, 1)
+
+	serveTimes(e, "/users/1", 3)
+
+	if access, summaries := countRecords(buf.String()); access != 1 || summaries != 0 {
+		t.Fatalf("expected 1 record and no summary, got %d and %d: %s", access, summaries, buf.String())
+	}
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	output := buf.String()
+	if _, summaries := countRecords(output); summaries != 1 || !strings.Contains(output, "suppressed_count=2") {
+		t.Errorf("expected a summary of 2 suppressed records, got: %s", output)
+	}
+
+	// the summary is logged once
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, summaries := countRecords(buf.String()); summaries != 1 {
+		t.Errorf("expected 1 summary, got: %s", buf.String())
+	}
+}
+
+func TestDedupIgnoresClientErrors(t *testing.T) {
+	var buf syncBuffer
+	e, m := newDedupTestServer(&buf, time.Hour, 1)
+
+	serveTimes(e, "/missing", 5)
+	_ = m.Shutdown(context.Background())
+
+	if access, summaries := countRecords(buf.String()); access != 5 || summaries != 0 {
+		t.Errorf("expected 5 records and no summary, got %d and %d: %s", access, summaries, buf.String())
+	}
+}
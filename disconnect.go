@@ -0,0 +1,96 @@
+package slogecho
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+)
+
+// StatusClientClosedRequest is the non-standard status code used (by Nginx) when
+// the client closed the connection before the response was sent.
+const StatusClientClosedRequest = 499
+
+const (
+	clientClosedRequestMessage = "client closed request"
+	deadlineExceededMessage    = "deadline exceeded"
+)
+
+// disconnect describes why the handler stopped: the client went away, or a
+// deadline expired.
+type disconnect struct {
+	deadline bool
+	cause    error
+}
+
+// classifyDisconnect converts an error returned while the request context was
+// canceled (client gone) or expired into a 499 or 504 *echo.HTTPError.
+//
+// before is the request context seen before the handler, after the one seen
+// once it returned: inner middlewares may have replaced it (eg:
+// middleware.ContextTimeout). Only a deadline is read from after, since inner
+// middlewares cancel their context once done. Errors wrapping
+// context.DeadlineExceeded are deadlines too.
+func classifyDisconnect(before context.Context, after context.Context, err error) (error, *disconnect) {
+	if err == nil {
+		return err, nil
+	}
+
+	var d *disconnect
+	switch {
+	case before.Err() != nil:
+		d = &disconnect{
+			deadline: errors.Is(before.Err(), context.DeadlineExceeded),
+			cause:    context.Cause(before),
+		}
+	case errors.Is(after.Err(), context.DeadlineExceeded):
+		d = &disconnect{deadline: true, cause: context.Cause(after)}
+	case errors.Is(err, context.DeadlineExceeded):
+		d = &disconnect{deadline: true, cause: context.DeadlineExceeded}
+	default:
+		return err, nil
+	}
+
+	ctxErr := context.Canceled
+	if d.deadline {
+		ctxErr = context.DeadlineExceeded
+	}
+
+	// make sure the cause is part of the error chain, for errors.Is
+	if !errors.Is(err, ctxErr) {
+		err = fmt.Errorf("%w: %w", ctxErr, err)
+	}
+
+	if d.deadline {
+		return echo.NewHTTPError(http.StatusGatewayTimeout, deadlineExceededMessage).Wrap(err), d
+	}
+
+	return echo.NewHTTPError(StatusClientClosedRequest, clientClosedRequestMessage).Wrap(err), d
+}
+
+// attrs returns the kind of disconnect ("canceled" or "deadline_exceeded"),
+// its cause (see context.Cause) and the number of response bytes written
+// before.
+func (d *disconnect) attrs(bytesWritten int64) []any {
+	kind := "canceled"
+	if d.deadline {
+		kind = "deadline_exceeded"
+	}
+
+	return []any{
+		slog.String("kind", kind),
+		slog.String("cause", d.cause.Error()),
+		slog.Int64("bytes_written", bytesWritten),
+	}
+}
+
+func isClientClosed(status int, err error) bool {
+	return status == StatusClientClosedRequest && errors.Is(err, context.Canceled)
+}
+
+func isDeadlineExceeded(status int, err error) bool {
+	return status == http.StatusGatewayTimeout && errors.Is(err, context.DeadlineExceeded)
+}
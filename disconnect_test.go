@@ -0,0 +1,123 @@
+package slogecho
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/labstack/echo/v5/middleware"
+)
+
+func TestDisconnectCause(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    func() (context.Context, context.CancelFunc)
+		expect []string
+	}{
+		{
+			name: "cancel cause",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancelCause(context.Background())
+				cancel(errors.New("peer reset"))
+				return ctx, func() {}
+			},
+			expect: []string{"response.status=499", "disconnect.kind=canceled", `disconnect.cause="peer reset"`},
+		},
+		{
+			name: "cancel",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			expect: []string{"response.status=499", "disconnect.kind=canceled", `disconnect.cause="context canceled"`},
+		},
+		{
+			name: "deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), -time.Second)
+			},
+			expect: []string{"response.status=504", "disconnect.kind=deadline_exceeded", `disconnect.cause="context deadline exceeded"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf syncBuffer
+
+			e := echo.New()
+			e.Use(New(slog.New(slog.NewTextHandler(&buf, nil))))
+			e.GET("/", func(c *echo.Context) error {
+				return c.Request().Context().Err()
+			})
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+
+			output := buf.String()
+			for _, expect := range tt.expect {
+				if !strings.Contains(output, expect) {
+					t.Errorf("expected %s, got: %s", expect, output)
+				}
+			}
+		})
+	}
+}
+
+func TestDisconnectInnerContextTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler echo.HandlerFunc
+		expect  []string
+		reject  []string
+	}{
+		{
+			name: "deadline",
+			handler: func(c *echo.Context) error {
+				<-c.Request().Context().Done()
+				return c.Request().Context().Err()
+			},
+			expect: []string{"response.status=504", "disconnect.kind=deadline_exceeded", `disconnect.cause="context deadline exceeded"`},
+		},
+		{
+			name: "error before the deadline",
+			handler: func(c *echo.Context) error {
+				return echo.ErrNotFound
+			},
+			expect: []string{"response.status=404"},
+			reject: []string{"disconnect."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf syncBuffer
+
+			e := echo.New()
+			e.Use(New(slog.New(slog.NewTextHandler(&buf, nil))))
+			e.Use(middleware.ContextTimeout(10 * time.Millisecond))
+			e.GET("/", tt.handler)
+
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			output := buf.String()
+			for _, expect := range tt.expect {
+				if !strings.Contains(output, expect) {
+					t.Errorf("expected %s, got: %s", expect, output)
+				}
+			}
+			for _, reject := range tt.reject {
+				if strings.Contains(output, reject) {
+					t.Errorf("unexpected %s, got: %s", reject, output)
+				}
+			}
+		})
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"mime"
 	"net"
 	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
 )
 
 var _ http.ResponseWriter = (*bodyWriter)(nil)
@@ -18,28 +22,83 @@ type bodyWriter struct {
 	http.ResponseWriter
 	body    *bytes.Buffer
 	maxSize int
-	bytes   int
+	bytes   atomic.Int64 // read concurrently by the watchdog
+
+	headerAt     time.Time
+	firstWriteAt time.Time
+	lastWriteAt  time.Time
+
+	// onHijack wraps the hijacked connection, when not nil
+	onHijack func(conn net.Conn, rw *bufio.ReadWriter) (net.Conn, *bufio.ReadWriter)
+
+	// streaming responses: text/event-stream or flushed
+	streaming       atomic.Bool
+	streamAt        time.Time
+	flushes         atomic.Int64
+	events          atomic.Int64 // server-sent events
+	eventStream     bool
+	eventStreamEOL  bool
+	contentTypeSeen bool
+	keepStreamBody  bool
+	// onStream is called once, when the response is detected as a stream
+	onStream func()
+
+	// bodies larger than maxSize are spooled, when openSpool is not nil
+	openSpool func() *spool
+	spool     *spool
+}
+
+// implements http.ResponseWriter
+func (w *bodyWriter) WriteHeader(statusCode int) {
+	if w.headerAt.IsZero() {
+		w.headerAt = time.Now()
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
 }
 
 // implements http.ResponseWriter
 func (w *bodyWriter) Write(b []byte) (int, error) {
 	length := len(b)
+	w.beforeWrite()
+	defer w.afterWrite()
+
+	if w.eventStream {
+		w.countEvents(b)
+	}
 
 	if w.body != nil {
-		if w.body.Len()+length > w.maxSize {
+		if w.spool == nil && w.openSpool != nil && w.body.Len()+length > w.maxSize {
+			w.spool = w.openSpool()
+			w.spool.write(w.body.Bytes())
+		}
+
+		switch {
+		case w.spool != nil && w.spool.err == nil:
+			w.spool.write(b)
+		case w.body.Len()+length > w.maxSize:
 			w.body.Truncate(min(w.maxSize, length, w.body.Len()))
 			w.body.Write(b[:min(w.maxSize-w.body.Len(), length)])
-		} else {
+		default:
 			w.body.Write(b)
 		}
 	}
 
-	w.bytes += length //nolint:staticcheck
+	w.bytes.Add(int64(length))
 	return w.ResponseWriter.Write(b)
 }
 
+// Unwrap returns the underlying http.ResponseWriter (see http.ResponseController and echo.UnwrapResponse)
+func (w *bodyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // implements http.Flusher
 func (w *bodyWriter) Flush() {
+	w.flushes.Add(1)
+	w.detectEventStream()
+	w.markStreaming()
+
 	if f, ok := w.ResponseWriter.(http.Flusher); ok {
 		f.Flush()
 	}
@@ -48,7 +107,11 @@ func (w *bodyWriter) Flush() {
 // implements http.Hijacker
 func (w *bodyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if hi, ok := w.ResponseWriter.(http.Hijacker); ok {
-		return hi.Hijack()
+		conn, rw, err := hi.Hijack()
+		if err == nil && w.onHijack != nil {
+			conn, rw = w.onHijack(conn, rw)
+		}
+		return conn, rw, err
 	}
 
 	return nil, nil, errors.New("Hijack not supported")
@@ -56,17 +119,111 @@ func (w *bodyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 
 // implements io.ReaderFrom
 func (w *bodyWriter) ReadFrom(r io.Reader) (int64, error) {
-	if w.body == nil {
+	w.detectEventStream()
+
+	if w.body == nil && !w.eventStream {
 		if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+			w.beforeWrite()
+			defer w.afterWrite()
+
 			n, err := rf.ReadFrom(r)
-			w.bytes += int(n)
+			w.bytes.Add(n)
 			return n, err
 		}
 	}
 	return io.Copy(struct{ io.Writer }{w}, r)
 }
 
-func newBodyWriter(writer http.ResponseWriter, maxSize int, recordBody bool) *bodyWriter {
+func (w *bodyWriter) beforeWrite() {
+	w.detectEventStream()
+
+	if w.firstWriteAt.IsZero() {
+		w.firstWriteAt = time.Now()
+	}
+
+	// the first Write sends the headers implicitly
+	if w.headerAt.IsZero() {
+		w.headerAt = w.firstWriteAt
+	}
+}
+
+func (w *bodyWriter) afterWrite() {
+	w.lastWriteAt = time.Now()
+}
+
+// detectEventStream checks the content type once, on the first write.
+func (w *bodyWriter) detectEventStream() {
+	if w.contentTypeSeen {
+		return
+	}
+	w.contentTypeSeen = true
+
+	if mediaType, _, err := mime.ParseMediaType(w.Header().Get("Content-Type")); err == nil && mediaType == "text/event-stream" {
+		w.eventStream = true
+		w.markStreaming()
+	}
+}
+
+// markStreaming flags the response as a stream. Unless keepStreamBody, the
+// body is not captured anymore: an open spool is truncated, and still logged.
+func (w *bodyWriter) markStreaming() {
+	if !w.streaming.CompareAndSwap(false, true) {
+		return
+	}
+
+	w.streamAt = time.Now()
+	if !w.keepStreamBody {
+		w.body = nil
+		if w.spool != nil {
+			w.spool.truncated = true
+		}
+	}
+	if w.onStream != nil {
+		w.onStream()
+	}
+}
+
+// countEvents counts the server-sent events, dispatched by an empty line.
+func (w *bodyWriter) countEvents(b []byte) {
+	for _, c := range b {
+		switch c {
+		case '\r':
+		case '\n':
+			if w.eventStreamEOL {
+				w.events.Add(1)
+			}
+			w.eventStreamEOL = !w.eventStreamEOL
+		default:
+			w.eventStreamEOL = false
+		}
+	}
+}
+
+// writeDuration returns the time spent between the first and the end of the last write.
+func (w *bodyWriter) writeDuration() time.Duration {
+	if w.firstWriteAt.IsZero() {
+		return 0
+	}
+
+	return w.lastWriteAt.Sub(w.firstWriteAt)
+}
+
+// responseContentLength returns the Content-Length header set by the handler,
+// for responses having a body.
+func responseContentLength(req *http.Request, w http.ResponseWriter, status int) (int64, bool) {
+	if req.Method == http.MethodHead || status < 200 || status == http.StatusNoContent || status == http.StatusNotModified {
+		return 0, false
+	}
+
+	n, err := strconv.ParseInt(w.Header().Get("Content-Length"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return n, true
+}
+
+func newBodyWriter(writer http.ResponseWriter, maxSize int, recordBody bool, recordStreamBody bool) *bodyWriter {
 	var body *bytes.Buffer
 	if recordBody {
 		body = bytes.NewBufferString("")
@@ -76,7 +233,7 @@ func newBodyWriter(writer http.ResponseWriter, maxSize int, recordBody bool) *bo
 		ResponseWriter: writer,
 		body:           body,
 		maxSize:        maxSize,
-		bytes:          0,
+		keepStreamBody: recordStreamBody,
 	}
 }
 
@@ -84,23 +241,120 @@ type bodyReader struct {
 	io.ReadCloser
 	body    *bytes.Buffer
 	maxSize int
-	bytes   int
+	bytes   atomic.Int64 // read concurrently by the watchdog
+
+	firstReadAt time.Time
+	lastReadAt  time.Time
+	blocked     time.Duration // time spent in Read
+	eof         bool
+
+	skip int       // bytes already captured (eager capture)
+	tee  io.Writer // receives the bytes read, when not nil
+
+	// bodies larger than maxSize are spooled, when openSpool is not nil
+	openSpool func() *spool
+	spool     *spool
 }
 
 // implements io.Reader
 func (r *bodyReader) Read(b []byte) (int, error) {
+	start := time.Now()
+	if r.firstReadAt.IsZero() {
+		r.firstReadAt = start
+	}
+
 	n, err := r.ReadCloser.Read(b)
-	if r.body != nil && r.body.Len() < r.maxSize {
-		if r.body.Len()+n > r.maxSize {
-			r.body.Write(b[:min(r.maxSize-r.body.Len(), n)])
-		} else {
-			r.body.Write(b[:n])
+
+	r.lastReadAt = time.Now()
+	r.blocked += r.lastReadAt.Sub(start)
+
+	if r.tee != nil && n > 0 {
+		_, _ = r.tee.Write(b[:n])
+	}
+
+	if r.body != nil {
+		data := b[:n]
+		if r.skip > 0 {
+			skipped := min(r.skip, len(data))
+			data = data[skipped:]
+			r.skip -= skipped
 		}
+
+		if r.spool == nil && r.openSpool != nil && r.body.Len()+len(data) > r.maxSize {
+			r.spool = r.openSpool()
+			r.spool.write(r.body.Bytes())
+		}
+
+		switch {
+		case r.spool != nil && r.spool.err == nil:
+			r.spool.write(data)
+		case r.body.Len()+len(data) > r.maxSize:
+			r.body.Write(data[:max(r.maxSize-r.body.Len(), 0)])
+		default:
+			r.body.Write(data)
+		}
+	}
+	r.bytes.Add(int64(n))
+	if err == io.EOF {
+		r.eof = true
 	}
-	r.bytes += n
 	return n, err
 }
 
+// drained returns true when the body has been read entirely.
+func (r *bodyReader) drained(contentLength int64) bool {
+	return r.eof || (contentLength >= 0 && r.bytes.Load() >= contentLength)
+}
+
+// readDuration returns the time spent between the first and the end of the last read.
+func (r *bodyReader) readDuration() time.Duration {
+	if r.firstReadAt.IsZero() {
+		return 0
+	}
+
+	return r.lastReadAt.Sub(r.firstReadAt)
+}
+
+// throughput returns the transfer rate in bytes per second.
+func throughput(bytes int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+
+	return float64(bytes) / d.Seconds()
+}
+
+// eagerReadBody reads the first bytes of the request body and replaces the
+// body with a reader replaying them. It returns nil when the body is not read.
+func eagerReadBody(req *http.Request, limit int) []byte {
+	if req.Body == nil || req.Body == http.NoBody || req.ContentLength == 0 {
+		return nil
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err == nil && mediaType == "multipart/form-data" {
+		limit = min(limit, EagerMultipartMaxSize)
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	// read errors are returned to the handler by the original body
+	prefix, _ := io.ReadAll(io.LimitReader(req.Body, int64(limit)))
+
+	req.Body = &replayBody{
+		Reader: io.MultiReader(bytes.NewReader(prefix), req.Body),
+		Closer: req.Body,
+	}
+
+	return prefix
+}
+
+// replayBody replays the bytes read eagerly, then streams the rest of the body.
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
 func newBodyReader(reader io.ReadCloser, maxSize int, recordBody bool) *bodyReader {
 	var body *bytes.Buffer
 	if recordBody {
@@ -111,6 +365,5 @@ func newBodyReader(reader io.ReadCloser, maxSize int, recordBody bool) *bodyRead
 		ReadCloser: reader,
 		body:       body,
 		maxSize:    maxSize,
-		bytes:      0,
 	}
 }
@@ -0,0 +1,111 @@
+package slogecho
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+)
+
+// numberedBody returns a body of numbered lines, so that a duplicated or
+// missing chunk changes the bytes.
+func numberedBody(size int) []byte {
+	var b bytes.Buffer
+	for i := 0; b.Len() < size; i++ {
+		fmt.Fprintf(&b, "%07d\n", i)
+	}
+	return b.Bytes()[:size]
+}
+
+func TestEagerRequestBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		read   bool
+		spool  bool
+		logged int // size of the body logged inline
+	}{
+		{name: "small body", size: 1000, read: true, logged: 1000},
+		{name: "small body not read", size: 1000, logged: 1000},
+		{name: "body larger than the eager limit", size: 3 * RequestBodyMaxSize, read: true, logged: RequestBodyMaxSize},
+		{name: "large body not read", size: 3 * RequestBodyMaxSize, logged: RequestBodyMaxSize},
+		{name: "spooled body", size: 3 * RequestBodyMaxSize, read: true, spool: true},
+		{name: "spooled body not read", size: 3 * RequestBodyMaxSize, spool: true, logged: RequestBodyMaxSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf syncBuffer
+			body := numberedBody(tt.size)
+			dir := t.TempDir()
+
+			config := DefaultConfig()
+			config.WithRequestBody = true
+			config.WithEagerRequestBody = true
+			if tt.spool {
+				spooler, err := NewDirSpooler(dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				config.BodySpooler = spooler
+				config.SpoolGzip = true
+				config.SpoolEncryptionKey = spoolTestKey
+			}
+
+			var received []byte
+			e := echo.New()
+			e.Use(NewWithConfig(slog.New(slog.NewJSONHandler(&buf, nil)), config))
+			e.POST("/webhook", func(c *echo.Context) error {
+				if tt.read {
+					var err error
+					if received, err = io.ReadAll(c.Request().Body); err != nil {
+						return err
+					}
+				}
+				return c.NoContent(204)
+			})
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook", bytes.NewReader(body)))
+
+			if tt.read && !bytes.Equal(received, body) {
+				t.Errorf("the handler received %d bytes, differing from the %d bytes sent", len(received), len(body))
+			}
+
+			var record struct {
+				Request struct {
+					Body      *string        `json:"body"`
+					BodySpool map[string]any `json:"body_spool"`
+				} `json:"request"`
+			}
+			if err := json.Unmarshal([]byte(buf.String()), &record); err != nil {
+				t.Fatal(err)
+			}
+
+			files := spooledFiles(t, dir)
+			if tt.logged == 0 {
+				if record.Request.BodySpool == nil || len(files) != 1 {
+					t.Fatalf("expected a spooled body, got: %s", buf.String())
+				}
+				spooled, err := readSpooledBody(files[0])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(spooled, body) {
+					t.Errorf("the %d spooled bytes differ from the %d bytes sent", len(spooled), len(body))
+				}
+				return
+			}
+
+			if len(files) != 0 {
+				t.Errorf("unexpected spooled body: %s", buf.String())
+			}
+			if record.Request.Body == nil || *record.Request.Body != string(body[:tt.logged]) {
+				t.Errorf("expected the %d first bytes of the body, got: %s", tt.logged, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package slogecho
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+)
+
+// ErrorChainMaxSize is the maximum number of errors logged from an error chain.
+var ErrorChainMaxSize = 32
+
+// ErrorClassification describes the error returned by a handler.
+type ErrorClassification struct {
+	// Status is the http status code. 0 means unknown (500).
+	Status int
+	// Code is a machine-readable error code, logged as "reason".
+	Code string
+	// Message is the public error message. Empty means http.StatusText(Status).
+	Message string
+}
+
+// ErrorClassifier classifies the error returned by a handler. It returns false
+// when the error is unknown, so that the built-in classification is used.
+type ErrorClassifier func(c *echo.Context, err error) (ErrorClassification, bool)
+
+// errorWithCode is implemented by errors carrying a machine-readable code.
+type errorWithCode interface {
+	ErrorCode() string
+}
+
+func (config Config) classifyError(c *echo.Context, err error) ErrorClassification {
+	if config.ErrorClassifier != nil {
+		if classification, ok := config.ErrorClassifier(c, err); ok {
+			return classification
+		}
+	}
+
+	return classifyError(err)
+}
+
+// classifyError looks for the first status code (see echo.HTTPStatusCoder),
+// error code and *echo.HTTPError message in the error tree.
+func classifyError(err error) ErrorClassification {
+	output := ErrorClassification{}
+
+	walkErrors(err, func(e error) bool {
+		if sc, ok := e.(echo.HTTPStatusCoder); ok && output.Status == 0 {
+			output.Status = sc.StatusCode()
+		}
+
+		if ec, ok := e.(errorWithCode); ok && output.Code == "" {
+			output.Code = ec.ErrorCode()
+		}
+
+		if he, ok := e.(*echo.HTTPError); ok && output.Message == "" {
+			output.Message = he.Message
+		}
+
+		return output.Status == 0 || output.Code == "" || output.Message == ""
+	})
+
+	return output
+}
+
+// newHTTPError converts a classified error into an *echo.HTTPError.
+func newHTTPError(err error, classification ErrorClassification) error {
+	status := classification.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	message := classification.Message
+	if message == "" {
+		message = http.StatusText(status)
+	}
+
+	return echo.NewHTTPError(status, message).Wrap(err)
+}
+
+// walkErrors visits the error tree (see errors.Unwrap and errors.Join) in
+// pre-order, until fn returns false.
+func walkErrors(err error, fn func(e error) bool) {
+	var walk func(e error) bool
+	walk = func(e error) bool {
+		if e == nil {
+			return true
+		}
+
+		if !fn(e) {
+			return false
+		}
+
+		switch x := e.(type) {
+		case interface{ Unwrap() error }:
+			return walk(x.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, child := range x.Unwrap() {
+				if !walk(child) {
+					return false
+				}
+			}
+		}
+
+		return true
+	}
+
+	walk(err)
+}
+
+// errorChain returns a structured representation of every error of the tree.
+func errorChain(err error) []map[string]any {
+	output := []map[string]any{}
+
+	walkErrors(err, func(e error) bool {
+		item := map[string]any{
+			"type":    fmt.Sprintf("%T", e),
+			"message": e.Error(),
+		}
+
+		if sc, ok := e.(echo.HTTPStatusCoder); ok {
+			item["status"] = sc.StatusCode()
+		}
+
+		if ec, ok := e.(errorWithCode); ok {
+			item["code"] = ec.ErrorCode()
+		}
+
+		if lv, ok := e.(slog.LogValuer); ok {
+			item["value"] = valueToAny(lv.LogValue())
+		}
+
+		output = append(output, item)
+		return len(output) < ErrorChainMaxSize
+	})
+
+	return output
+}
+
+// valueToAny converts a slog.Value into a plain value, groups being converted to maps.
+func valueToAny(v slog.Value) any {
+	v = v.Resolve()
+	if v.Kind() != slog.KindGroup {
+		return v.Any()
+	}
+
+	output := map[string]any{}
+	for _, attr := range v.Group() {
+		output[attr.Key] = valueToAny(attr.Value)
+	}
+	return output
+}
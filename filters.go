@@ -214,21 +214,34 @@ func IgnorePathMatch(regs ...regexp.Regexp) Filter {
 }
 
 // Host
+//
+// Host filters match the Host header of the request. Hostnames are lowercased
+// and converted to punycode. The port is ignored, except by AcceptHost and
+// IgnoreHost, whose patterns may contain a port ("example.com:8080") and a
+// wildcard subdomain ("*.example.com").
 func AcceptHost(hosts ...string) Filter {
+	patterns := newHostPatterns(hosts)
+
 	return func(c *echo.Context, err error) bool {
-		return slices.Contains(hosts, c.Request().URL.Host)
+		host, port := requestHost(c)
+		return matchHostPatterns(patterns, host, port)
 	}
 }
 
 func IgnoreHost(hosts ...string) Filter {
+	patterns := newHostPatterns(hosts)
+
 	return func(c *echo.Context, err error) bool {
-		return !slices.Contains(hosts, c.Request().URL.Host)
+		host, port := requestHost(c)
+		return !matchHostPatterns(patterns, host, port)
 	}
 }
 
 func AcceptHostContains(parts ...string) Filter {
+	parts = normalizeHostParts(parts)
+
 	return func(c *echo.Context, err error) bool {
-		host := c.Request().URL.Host
+		host, _ := requestHost(c)
 		for _, part := range parts {
 			if strings.Contains(host, part) {
 				return true
@@ -240,8 +253,10 @@ func AcceptHostContains(parts ...string) Filter {
 }
 
 func IgnoreHostContains(parts ...string) Filter {
+	parts = normalizeHostParts(parts)
+
 	return func(c *echo.Context, err error) bool {
-		host := c.Request().URL.Host
+		host, _ := requestHost(c)
 		for _, part := range parts {
 			if strings.Contains(host, part) {
 				return false
@@ -253,8 +268,10 @@ func IgnoreHostContains(parts ...string) Filter {
 }
 
 func AcceptHostPrefix(prefixs ...string) Filter {
+	prefixs = normalizeHostParts(prefixs)
+
 	return func(c *echo.Context, err error) bool {
-		host := c.Request().URL.Host
+		host, _ := requestHost(c)
 		for _, prefix := range prefixs {
 			if strings.HasPrefix(host, prefix) {
 				return true
@@ -266,8 +283,10 @@ func AcceptHostPrefix(prefixs ...string) Filter {
 }
 
 func IgnoreHostPrefix(prefixs ...string) Filter {
+	prefixs = normalizeHostParts(prefixs)
+
 	return func(c *echo.Context, err error) bool {
-		host := c.Request().URL.Host
+		host, _ := requestHost(c)
 		for _, prefix := range prefixs {
 			if strings.HasPrefix(host, prefix) {
 				return false
@@ -279,8 +298,10 @@ func IgnoreHostPrefix(prefixs ...string) Filter {
 }
 
 func AcceptHostSuffix(suffixs ...string) Filter {
+	suffixs = normalizeHostParts(suffixs)
+
 	return func(c *echo.Context, err error) bool {
-		host := c.Request().URL.Host
+		host, _ := requestHost(c)
 		for _, suffix := range suffixs {
 			if strings.HasSuffix(host, suffix) {
 				return true
@@ -292,8 +313,10 @@ func AcceptHostSuffix(suffixs ...string) Filter {
 }
 
 func IgnoreHostSuffix(suffixs ...string) Filter {
+	suffixs = normalizeHostParts(suffixs)
+
 	return func(c *echo.Context, err error) bool {
-		host := c.Request().URL.Host
+		host, _ := requestHost(c)
 		for _, suffix := range suffixs {
 			if strings.HasSuffix(host, suffix) {
 				return false
@@ -306,7 +329,7 @@ func IgnoreHostSuffix(suffixs ...string) Filter {
 
 func AcceptHostMatch(regs ...regexp.Regexp) Filter {
 	return func(c *echo.Context, err error) bool {
-		host := c.Request().URL.Host
+		host, _ := requestHost(c)
 		for _, reg := range regs {
 			if reg.MatchString(host) {
 				return true
@@ -319,7 +342,7 @@ func AcceptHostMatch(regs ...regexp.Regexp) Filter {
 
 func IgnoreHostMatch(regs ...regexp.Regexp) Filter {
 	return func(c *echo.Context, err error) bool {
-		host := c.Request().URL.Host
+		host, _ := requestHost(c)
 		for _, reg := range regs {
 			if reg.MatchString(host) {
 				return false
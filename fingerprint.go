@@ -0,0 +1,55 @@
+package slogecho
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+)
+
+var (
+	fingerprintUUIDRegexp   = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	fingerprintHexRegexp    = regexp.MustCompile(`(?i)\b(0x[0-9a-f]+|[0-9a-f]*[0-9][0-9a-f]*[a-f][0-9a-f]*|[0-9a-f]*[a-f][0-9a-f]*[0-9][0-9a-f]*)\b`)
+	fingerprintNumberRegexp = regexp.MustCompile(`\d+`)
+)
+
+// NormalizeErrorMessage is the default error message normalizer used by the
+// error fingerprint. It replaces UUIDs, hexadecimal values and numbers by
+// placeholders, so that "user 42 not found" and "user 43 not found" are equal.
+func NormalizeErrorMessage(msg string) string {
+	msg = fingerprintUUIDRegexp.ReplaceAllString(msg, "<uuid>")
+	msg = fingerprintHexRegexp.ReplaceAllString(msg, "<hex>")
+	msg = fingerprintNumberRegexp.ReplaceAllString(msg, "<n>")
+	return msg
+}
+
+// errorFingerprint returns a stable hash of the route template, the types of
+// the error chain and the normalized error message.
+func errorFingerprint(route string, httpErr *echo.HTTPError, normalize func(string) string) string {
+	if normalize == nil {
+		normalize = NormalizeErrorMessage
+	}
+
+	var err error = httpErr
+	if internal := httpErr.Unwrap(); internal != nil {
+		err = internal
+	}
+
+	types := []string{}
+	walkErrors(err, func(e error) bool {
+		types = append(types, fmt.Sprintf("%T", e))
+		return len(types) < ErrorChainMaxSize
+	})
+
+	h := sha256.New()
+	h.Write([]byte(route))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(types, ",")))
+	h.Write([]byte{0})
+	h.Write([]byte(normalize(err.Error())))
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
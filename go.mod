@@ -3,13 +3,17 @@ module github.com/samber/slog-echo/v2
 go 1.25.0
 
 require (
+	github.com/andybalholm/brotli v1.2.6
+	github.com/klauspost/compress v1.20.1
 	github.com/labstack/echo/v5 v5.2.1
 	github.com/samber/lo v1.53.0
 	go.opentelemetry.io/otel/trace v1.44.0
+	golang.org/x/net v0.49.0
 )
 
 require (
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	go.opentelemetry.io/otel v1.44.0 // indirect
 	golang.org/x/text v0.33.0 // indirect
+	golang.org/x/time v0.14.0 // indirect
 )
@@ -0,0 +1,341 @@
+package slogecho
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// hijackedConn wraps a hijacked connection (eg: WebSocket), to log a record
+// when the session ends.
+type hijackedConn struct {
+	net.Conn
+	start time.Time
+
+	bytesIn   atomic.Int64
+	bytesOut  atomic.Int64
+	framesIn  *wsFrameCounter // nil when frames are not counted
+	framesOut *wsFrameCounter // nil when frames are not counted
+
+	mu        sync.Mutex
+	closeErr  error  // first read/write error
+	initiator string // side sending the first WebSocket close frame
+	once      sync.Once
+	onClose   func(c *hijackedConn)
+}
+
+func (c *hijackedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.bytesIn.Add(int64(n))
+	if c.framesIn != nil && c.framesIn.feed(b[:n]) {
+		c.setInitiator("client")
+	}
+	if err != nil {
+		c.setCloseErr(err)
+	}
+	return n, err
+}
+
+func (c *hijackedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.bytesOut.Add(int64(n))
+	if c.framesOut != nil && c.framesOut.feed(b[:n]) {
+		c.setInitiator("server")
+	}
+	if err != nil {
+		c.setCloseErr(err)
+	}
+	return n, err
+}
+
+func (c *hijackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() { c.onClose(c) })
+	return err
+}
+
+func (c *hijackedConn) setCloseErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closeErr == nil {
+		c.closeErr = err
+	}
+}
+
+func (c *hijackedConn) setInitiator(side string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.initiator == "" {
+		c.initiator = side
+	}
+}
+
+func (c *hijackedConn) closeReason() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case c.initiator != "":
+		return "closed by " + c.initiator
+	case c.closeErr == nil:
+		return "closed by server"
+	case errors.Is(c.closeErr, io.EOF):
+		return "closed by client"
+	default:
+		return c.closeErr.Error()
+	}
+}
+
+// wrapHijackedConn wraps the connection and the buffered reader/writer
+// returned by http.Hijacker.
+func (m *Middleware) wrapHijackedConn(conn net.Conn, rw *bufio.ReadWriter, onClose func(c *hijackedConn)) (net.Conn, *bufio.ReadWriter) {
+	wrapped := &hijackedConn{
+		Conn:    conn,
+		start:   time.Now(),
+		onClose: onClose,
+	}
+
+	if m.config.WithWebSocketFrames {
+		wrapped.framesIn = &wsFrameCounter{}
+		wrapped.framesOut = &wsFrameCounter{}
+	}
+
+	if rw == nil {
+		return wrapped, rw
+	}
+
+	// bytes read from the connection before the hijack
+	var reader *bufio.Reader
+	if n := rw.Reader.Buffered(); n > 0 {
+		buffered, _ := rw.Reader.Peek(n)
+		buffered = bytes.Clone(buffered)
+
+		wrapped.bytesIn.Add(int64(n))
+		if wrapped.framesIn != nil && wrapped.framesIn.feed(buffered) {
+			wrapped.setInitiator("client")
+		}
+
+		reader = bufio.NewReader(io.MultiReader(bytes.NewReader(buffered), wrapped))
+	} else {
+		reader = rw.Reader
+		reader.Reset(wrapped)
+	}
+
+	writer := rw.Writer
+	if writer.Flush() == nil {
+		writer.Reset(wrapped)
+	}
+
+	return wrapped, bufio.NewReadWriter(reader, writer)
+}
+
+// logHijackedConn logs the end of a hijacked connection session.
+func (m *Middleware) logHijackedConn(r *inflightRequest, requestID string, correlationID string, c *hijackedConn) {
+	sessionAttributes := []any{
+		slog.Time("time", c.start.UTC()),
+		slog.Duration("duration", time.Since(c.start)),
+		slog.Int64("bytes_in", c.bytesIn.Load()),
+		slog.Int64("bytes_out", c.bytesOut.Load()),
+		slog.String("close_reason", c.closeReason()),
+	}
+
+	if c.framesIn != nil {
+		closeCode := c.framesOut.code()
+		if c.closeReason() == "closed by client" && c.framesIn.code() != 0 {
+			closeCode = c.framesIn.code()
+		}
+
+		sessionAttributes = append(
+			sessionAttributes,
+			slog.Group(
+				"websocket",
+				slog.Int("frames_in", c.framesIn.framesCount()),
+				slog.Int("frames_out", c.framesOut.framesCount()),
+				slog.Int("messages_in", c.framesIn.messagesCount()),
+				slog.Int("messages_out", c.framesOut.messagesCount()),
+				slog.Int("close_code", closeCode),
+			),
+		)
+	}
+
+	attributes := []slog.Attr{
+		slog.Group(
+			"request",
+			slog.Time("time", r.start.UTC()),
+			slog.String("method", r.method),
+			slog.String("path", r.path),
+			slog.String("route", r.route),
+		),
+		slog.Group("session", sessionAttributes...),
+	}
+
+	if m.config.WithRequestID && requestID != "" {
+		attributes = append(attributes, slog.String(RequestIDKey, requestID))
+	}
+
+	if correlationID != "" {
+		attributes = append(attributes, slog.String(CorrelationIDKey, correlationID))
+	}
+
+	m.logger.LogAttrs(r.ctx, m.config.DefaultLevel, "Hijacked connection closed", attributes...)
+}
+
+// wsFrameCounter counts the WebSocket frames (RFC 6455) of one direction of
+// a connection, by parsing the frame headers of the byte stream.
+type wsFrameCounter struct {
+	mu sync.Mutex
+
+	handshake  []byte // HTTP upgrade handshake written after the hijack
+	header     []byte
+	payload    uint64 // remaining payload bytes of the current frame
+	offset     uint64 // payload bytes already consumed
+	opcode     byte
+	mask       [4]byte
+	masked     bool
+	closeFrame []byte
+
+	frames    int
+	messages  int
+	closed    bool
+	closeCode int
+}
+
+const (
+	wsOpContinuation = 0x0
+	wsOpClose        = 0x8
+)
+
+// feed parses the bytes of the stream. It returns true when a close frame has been seen.
+func (f *wsFrameCounter) feed(b []byte) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for len(b) > 0 {
+		// "H" cannot start a valid frame (fragmented control frame), so it
+		// starts the HTTP handshake, skipped until the empty line.
+		if f.frames == 0 && len(f.header) == 0 && (f.handshake != nil || b[0] == 'H') {
+			i := bytes.Index(append(f.handshake, b...), []byte("\r\n\r\n"))
+			if i < 0 {
+				f.handshake = append(f.handshake, b[max(0, len(b)-3):]...)
+				f.handshake = f.handshake[max(0, len(f.handshake)-3):]
+				return f.closed
+			}
+			b = b[i+4-len(f.handshake):]
+			f.handshake = nil
+			continue
+		}
+
+		if f.payload > 0 {
+			n := min(uint64(len(b)), f.payload)
+			if f.opcode == wsOpClose && len(f.closeFrame) < 2 {
+				for i := uint64(0); i < n && len(f.closeFrame) < 2; i++ {
+					c := b[i]
+					if f.masked {
+						c ^= f.mask[(f.offset+i)%4]
+					}
+					f.closeFrame = append(f.closeFrame, c)
+				}
+				if len(f.closeFrame) == 2 {
+					f.closeCode = int(binary.BigEndian.Uint16(f.closeFrame))
+				}
+			}
+
+			f.payload -= n
+			f.offset += n
+			b = b[n:]
+			continue
+		}
+
+		f.header = append(f.header, b[0])
+		b = b[1:]
+
+		size, ok := f.headerSize()
+		if !ok || len(f.header) < size {
+			continue
+		}
+
+		f.parseHeader()
+		f.header = f.header[:0]
+	}
+
+	return f.closed
+}
+
+// headerSize returns the size of the frame header, once known.
+func (f *wsFrameCounter) headerSize() (int, bool) {
+	if len(f.header) < 2 {
+		return 0, false
+	}
+
+	size := 2
+	switch f.header[1] & 0x7f {
+	case 126:
+		size += 2
+	case 127:
+		size += 8
+	}
+	if f.header[1]&0x80 != 0 {
+		size += 4
+	}
+
+	return size, true
+}
+
+func (f *wsFrameCounter) parseHeader() {
+	f.opcode = f.header[0] & 0x0f
+	f.masked = f.header[1]&0x80 != 0
+	f.offset = 0
+
+	rest := f.header[2:]
+	switch length := f.header[1] & 0x7f; length {
+	case 126:
+		f.payload = uint64(binary.BigEndian.Uint16(rest))
+		rest = rest[2:]
+	case 127:
+		f.payload = binary.BigEndian.Uint64(rest)
+		rest = rest[8:]
+	default:
+		f.payload = uint64(length)
+	}
+
+	if f.masked {
+		copy(f.mask[:], rest)
+	}
+
+	f.frames++
+	switch {
+	case f.opcode == wsOpClose:
+		f.closed = true
+		f.closeFrame = f.closeFrame[:0]
+	case f.opcode != wsOpContinuation && f.opcode < wsOpClose:
+		// first frame of a text or binary message
+		f.messages++
+	}
+}
+
+func (f *wsFrameCounter) framesCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.frames
+}
+
+func (f *wsFrameCounter) messagesCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.messages
+}
+
+func (f *wsFrameCounter) code() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closeCode
+}
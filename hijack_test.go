@@ -0,0 +1,227 @@
+package slogecho
+
+import (
+	"bufio"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/labstack/echo/v5/middleware"
+)
+
+// hijackableRecorder is a httptest.ResponseRecorder supporting http.Hijacker.
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (r *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return r.conn, bufio.NewReadWriter(bufio.NewReader(r.conn), bufio.NewWriter(r.conn)), nil
+}
+
+func TestHijackedConnRequestID(t *testing.T) {
+	var buf syncBuffer
+
+	config := DefaultConfig()
+	config.WithHijackedConnections = true
+
+	e := echo.New()
+	e.Use(NewWithConfig(slog.New(slog.NewTextHandler(&buf, nil)), config))
+	e.Use(middleware.RequestID())
+	e.GET("/ws", func(c *echo.Context) error {
+		conn, _, err := http.NewResponseController(c.Response()).Hijack()
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	rec := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server}
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/ws", nil))
+
+	requestID := rec.Header().Get(echo.HeaderXRequestID)
+	if requestID == "" {
+		t.Fatal("expected a request id")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got: %v", lines)
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, "id="+requestID) {
+			t.Errorf("expected id=%s, got: %s", requestID, line)
+		}
+	}
+	if !strings.Contains(lines[0], `msg="Hijacked connection closed"`) {
+		t.Errorf("expected the session record first, got: %s", lines[0])
+	}
+}
+
+// wsFrame encodes a WebSocket frame, masked when mask is not nil.
+func wsFrame(fin bool, opcode byte, payload []byte, mask []byte) []byte {
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+
+	var maskBit byte
+	if mask != nil {
+		maskBit = 0x80
+	}
+
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	if mask == nil {
+		return append(frame, payload...)
+	}
+
+	frame = append(frame, mask...)
+	for i, c := range payload {
+		frame = append(frame, c^mask[i%4])
+	}
+	return frame
+}
+
+func wsCloseFrame(code uint16, mask []byte) []byte {
+	return wsFrame(true, wsOpClose, binary.BigEndian.AppendUint16(nil, code), mask)
+}
+
+func concat(chunks ...[]byte) []byte {
+	var b []byte
+	for _, chunk := range chunks {
+		b = append(b, chunk...)
+	}
+	return b
+}
+
+func TestWsFrameCounter(t *testing.T) {
+	mask := []byte{0x37, 0xfa, 0x21, 0x3d}
+	handshake := []byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+
+	tests := []struct {
+		name      string
+		stream    []byte
+		frames    int
+		messages  int
+		closed    bool
+		closeCode int
+	}{
+		{
+			name:     "text frame",
+			stream:   wsFrame(true, 0x1, []byte("hello"), nil),
+			frames:   1,
+			messages: 1,
+		},
+		{
+			name:     "handshake skipped",
+			stream:   concat(handshake, wsFrame(true, 0x1, []byte("hello"), nil), wsFrame(true, 0x2, []byte{0, 1, 2}, nil)),
+			frames:   2,
+			messages: 2,
+		},
+		{
+			name:     "masked frames",
+			stream:   concat(wsFrame(true, 0x1, []byte("hello"), mask), wsFrame(true, 0x2, []byte("world"), mask)),
+			frames:   2,
+			messages: 2,
+		},
+		{
+			name: "fragmented message with an interleaved ping",
+			stream: concat(
+				wsFrame(false, 0x1, []byte("hel"), mask),
+				wsFrame(true, 0x9, []byte("ping"), mask),
+				wsFrame(false, wsOpContinuation, []byte("lo "), mask),
+				wsFrame(true, wsOpContinuation, []byte("world"), mask),
+			),
+			frames:   4,
+			messages: 1,
+		},
+		{
+			name:     "16-bit length",
+			stream:   concat(wsFrame(true, 0x2, make([]byte, 300), nil), wsFrame(true, 0x1, []byte("x"), nil)),
+			frames:   2,
+			messages: 2,
+		},
+		{
+			name:     "64-bit length",
+			stream:   concat(wsFrame(true, 0x2, make([]byte, 70000), mask), wsFrame(true, 0x1, []byte("x"), mask)),
+			frames:   2,
+			messages: 2,
+		},
+		{
+			name:      "close frame",
+			stream:    concat(handshake, wsFrame(true, 0x1, []byte("bye"), nil), wsCloseFrame(1000, nil)),
+			frames:    2,
+			messages:  1,
+			closed:    true,
+			closeCode: 1000,
+		},
+		{
+			name:      "masked close frame",
+			stream:    concat(wsFrame(true, 0x1, []byte("bye"), mask), wsCloseFrame(1001, mask)),
+			frames:    2,
+			messages:  1,
+			closed:    true,
+			closeCode: 1001,
+		},
+		{
+			name:   "close frame without code",
+			stream: wsFrame(true, wsOpClose, nil, mask),
+			frames: 1,
+			closed: true,
+		},
+	}
+
+	feeds := map[string]func(f *wsFrameCounter, b []byte) bool{
+		"one chunk": func(f *wsFrameCounter, b []byte) bool {
+			return f.feed(b)
+		},
+		"byte by byte": func(f *wsFrameCounter, b []byte) bool {
+			closed := false
+			for i := range b {
+				closed = f.feed(b[i : i+1])
+			}
+			return closed
+		},
+	}
+
+	for _, tt := range tests {
+		for feedName, feed := range feeds {
+			t.Run(tt.name+"/"+feedName, func(t *testing.T) {
+				f := &wsFrameCounter{}
+				closed := feed(f, tt.stream)
+
+				if closed != tt.closed {
+					t.Errorf("closed: got %v, want %v", closed, tt.closed)
+				}
+				if got := f.framesCount(); got != tt.frames {
+					t.Errorf("frames: got %d, want %d", got, tt.frames)
+				}
+				if got := f.messagesCount(); got != tt.messages {
+					t.Errorf("messages: got %d, want %d", got, tt.messages)
+				}
+				if got := f.code(); got != tt.closeCode {
+					t.Errorf("close code: got %d, want %d", got, tt.closeCode)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package slogecho
+
+import (
+	"net"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+	"golang.org/x/net/idna"
+)
+
+// hostPattern is a pre-normalized pattern used by AcceptHost and IgnoreHost.
+// A leading "*." matches any subdomain (but not the apex domain).
+// An empty port matches any port.
+type hostPattern struct {
+	host     string
+	port     string
+	wildcard bool
+}
+
+func newHostPattern(pattern string) hostPattern {
+	host, port := splitHostPort(pattern)
+
+	wildcard := strings.HasPrefix(host, "*.")
+	if wildcard {
+		// keep the leading dot, so that "*.example.com" does not match "example.com"
+		return hostPattern{
+			host:     "." + normalizeHostname(host[2:]),
+			port:     port,
+			wildcard: true,
+		}
+	}
+
+	return hostPattern{
+		host:     normalizeHostname(host),
+		port:     port,
+		wildcard: false,
+	}
+}
+
+func (p hostPattern) match(host string, port string) bool {
+	if p.port != "" && p.port != port {
+		return false
+	}
+
+	if p.wildcard {
+		return strings.HasSuffix(host, p.host)
+	}
+
+	return host == p.host
+}
+
+func newHostPatterns(patterns []string) []hostPattern {
+	output := make([]hostPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		output = append(output, newHostPattern(pattern))
+	}
+	return output
+}
+
+func matchHostPatterns(patterns []hostPattern, host string, port string) bool {
+	for _, pattern := range patterns {
+		if pattern.match(host, port) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestHost returns the normalized hostname and port of the request.
+// The hostname is lowercased, stripped from its trailing dot and converted to
+// punycode. When the port is missing, the default port of the scheme is used.
+func requestHost(c *echo.Context) (string, string) {
+	raw := c.Request().Host
+	if raw == "" {
+		raw = c.Request().URL.Host
+	}
+
+	host, port := splitHostPort(raw)
+	if port == "" {
+		port = "80"
+		if c.Scheme() == "https" {
+			port = "443"
+		}
+	}
+
+	return normalizeHostname(host), port
+}
+
+// splitHostPort is a lenient version of net.SplitHostPort: a missing port is
+// not an error and IPv6 brackets are removed.
+func splitHostPort(hostport string) (string, string) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+		port = ""
+	}
+
+	host = strings.TrimPrefix(host, "[")
+	host = strings.TrimSuffix(host, "]")
+
+	return host, port
+}
+
+func normalizeHostname(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		return ascii
+	}
+
+	return host
+}
+
+// normalizeHostPart normalizes a partial hostname (prefix, suffix...), label by
+// label, so that it can be compared to a normalized hostname.
+func normalizeHostPart(part string) string {
+	labels := strings.Split(strings.ToLower(part), ".")
+	for i, label := range labels {
+		if ascii, err := idna.ToASCII(label); err == nil {
+			labels[i] = ascii
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+func normalizeHostParts(parts []string) []string {
+	output := make([]string, 0, len(parts))
+	for _, part := range parts {
+		output = append(output, normalizeHostPart(part))
+	}
+	return output
+}
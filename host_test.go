@@ -0,0 +1,93 @@
+package slogecho
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+)
+
+func newHostContext(host string, https bool) *echo.Context {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = host
+	if https {
+		req.TLS = &tls.ConnectionState{}
+	}
+
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestRequestHost(t *testing.T) {
+	tests := []struct {
+		name  string
+		host  string
+		https bool
+		want  string
+		port  string
+	}{
+		{name: "without port", host: "example.com", want: "example.com", port: "80"},
+		{name: "with port", host: "example.com:8080", want: "example.com", port: "8080"},
+		{name: "default https port", host: "example.com", https: true, want: "example.com", port: "443"},
+		{name: "uppercase", host: "EXAMPLE.com", want: "example.com", port: "80"},
+		{name: "ipv6 with port", host: "[::1]:8080", want: "::1", port: "8080"},
+		{name: "ipv6 without port", host: "[::1]", want: "::1", port: "80"},
+		{name: "trailing dot", host: "example.com.", want: "example.com", port: "80"},
+		{name: "trailing dot with port", host: "example.com.:8080", want: "example.com", port: "8080"},
+		{name: "idn", host: "bücher.example", want: "xn--bcher-kva.example", port: "80"},
+		{name: "punycode", host: "xn--bcher-kva.example", want: "xn--bcher-kva.example", port: "80"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port := requestHost(newHostContext(tt.host, tt.https))
+			if host != tt.want || port != tt.port {
+				t.Errorf("requestHost(%q) = %q, %q, want %q, %q", tt.host, host, port, tt.want, tt.port)
+			}
+		})
+	}
+}
+
+func TestHostFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		host   string
+		https  bool
+		want   bool
+	}{
+		{name: "exact", filter: AcceptHost("example.com"), host: "example.com", want: true},
+		{name: "exact with request port", filter: AcceptHost("example.com"), host: "example.com:8080", want: true},
+		{name: "other host", filter: AcceptHost("example.com"), host: "example.org", want: false},
+		{name: "pattern port", filter: AcceptHost("example.com:8080"), host: "example.com:8080", want: true},
+		{name: "pattern port mismatch", filter: AcceptHost("example.com:8080"), host: "example.com:9090", want: false},
+		{name: "pattern port from scheme", filter: AcceptHost("example.com:443"), host: "example.com", https: true, want: true},
+		{name: "pattern port from scheme mismatch", filter: AcceptHost("example.com:443"), host: "example.com", want: false},
+		{name: "ipv6", filter: AcceptHost("[::1]:8080"), host: "[::1]:8080", want: true},
+		{name: "trailing dot", filter: AcceptHost("example.com"), host: "example.com.", want: true},
+		{name: "pattern trailing dot", filter: AcceptHost("example.com."), host: "example.com", want: true},
+		{name: "idn pattern, punycode host", filter: AcceptHost("bücher.example"), host: "xn--bcher-kva.example", want: true},
+		{name: "punycode pattern, idn host", filter: AcceptHost("xn--bcher-kva.example"), host: "bücher.example", want: true},
+		{name: "wildcard subdomain", filter: AcceptHost("*.tenant.example.com"), host: "acme.tenant.example.com", want: true},
+		{name: "wildcard nested subdomain", filter: AcceptHost("*.tenant.example.com"), host: "a.b.tenant.example.com", want: true},
+		{name: "wildcard apex", filter: AcceptHost("*.tenant.example.com"), host: "tenant.example.com", want: false},
+		{name: "wildcard lookalike", filter: AcceptHost("*.tenant.example.com"), host: "eviltenant.example.com", want: false},
+		{name: "ignore wildcard subdomain", filter: IgnoreHost("*.tenant.example.com"), host: "acme.tenant.example.com", want: false},
+		{name: "ignore wildcard apex", filter: IgnoreHost("*.tenant.example.com"), host: "tenant.example.com", want: true},
+		{name: "suffix idn", filter: AcceptHostSuffix(".bücher.example"), host: "shop.xn--bcher-kva.example", want: true},
+		{name: "prefix", filter: AcceptHostPrefix("API."), host: "api.example.com:8080", want: true},
+		{name: "contains", filter: IgnoreHostContains("internal"), host: "svc.internal.example.com", want: false},
+		{name: "regexp without port", filter: AcceptHostMatch(*regexp.MustCompile(`^api\.example\.com$`)), host: "API.example.com:8080", want: true},
+		{name: "regexp with port", filter: AcceptHostMatch(*regexp.MustCompile(`:8080$`)), host: "api.example.com:8080", want: false},
+		{name: "regexp punycode", filter: AcceptHostMatch(*regexp.MustCompile(`^xn--bcher-kva\.`)), host: "bücher.example", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter(newHostContext(tt.host, tt.https), nil); got != tt.want {
+				t.Errorf("filter(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package slogecho
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// inflightRequest holds the state of a request being handled by the middleware.
+// Fields are set before calling the handler, so that they can be read
+// concurrently.
+type inflightRequest struct {
+	ctx       context.Context
+	start     time.Time
+	method    string
+	path      string
+	route     string
+	ip        string
+	requestID string
+	traceID   string
+	br        *bodyReader
+	bw        *bodyWriter
+
+	aborted atomic.Bool
+}
+
+func newInflightRequest(c *echo.Context, start time.Time, br *bodyReader, bw *bodyWriter) *inflightRequest {
+	req := c.Request()
+
+	traceID := ""
+	if spanCtx := trace.SpanContextFromContext(req.Context()); spanCtx.HasTraceID() {
+		traceID = spanCtx.TraceID().String()
+	}
+
+	return &inflightRequest{
+		ctx:       req.Context(),
+		start:     start,
+		method:    req.Method,
+		path:      req.URL.Path,
+		route:     c.Path(),
+		ip:        c.RealIP(),
+		requestID: getRequestID(c),
+		traceID:   traceID,
+		br:        br,
+		bw:        bw,
+	}
+}
+
+// InFlightRequest is a snapshot of a request being handled.
+type InFlightRequest struct {
+	Start        time.Time     `json:"start"`
+	Elapsed      time.Duration `json:"elapsed"`
+	Method       string        `json:"method"`
+	Path         string        `json:"path"`
+	Route        string        `json:"route"`
+	IP           string        `json:"ip,omitempty"`
+	RequestID    string        `json:"request_id,omitempty"`
+	TraceID      string        `json:"trace_id,omitempty"`
+	BytesRead    int64         `json:"bytes_read"`
+	BytesWritten int64         `json:"bytes_written"`
+}
+
+func (r *inflightRequest) snapshot(now time.Time) InFlightRequest {
+	return InFlightRequest{
+		Start:        r.start,
+		Elapsed:      now.Sub(r.start),
+		Method:       r.method,
+		Path:         r.path,
+		Route:        r.route,
+		IP:           r.ip,
+		RequestID:    r.requestID,
+		TraceID:      r.traceID,
+		BytesRead:    r.br.bytes.Load(),
+		BytesWritten: r.bw.bytes.Load(),
+	}
+}
+
+// inflightAttributes returns the attributes of the records logged while the
+// request is running (watchdog, shutdown).
+func (m *Middleware) inflightAttributes(r *inflightRequest, now time.Time) []slog.Attr {
+	requestAttributes := []any{
+		slog.Time("time", r.start.UTC()),
+		slog.String("method", r.method),
+		slog.String("path", r.path),
+		slog.String("route", r.route),
+	}
+	if m.config.WithClientIP {
+		requestAttributes = append(requestAttributes, slog.String("ip", r.ip))
+	}
+	requestAttributes = append(requestAttributes, slog.Int("length", int(r.br.bytes.Load())))
+
+	attributes := []slog.Attr{
+		slog.Group("request", requestAttributes...),
+		slog.Group(
+			"response",
+			slog.Int("length", int(r.bw.bytes.Load())),
+		),
+		slog.Duration("elapsed", now.Sub(r.start)),
+	}
+
+	if m.config.WithRequestID && r.requestID != "" {
+		attributes = append(attributes, slog.String(RequestIDKey, r.requestID))
+	}
+
+	if m.config.WithTraceID && r.traceID != "" {
+		attributes = append(attributes, slog.String(TraceIDKey, r.traceID))
+	}
+
+	return attributes
+}
+
+// logAborted logs the requests still running, once.
+func (m *Middleware) logAborted(ctx context.Context) error {
+	m.inflightMu.Lock()
+	requests := make([]*inflightRequest, 0, len(m.inflight))
+	for r := range m.inflight {
+		requests = append(requests, r)
+	}
+	m.inflightMu.Unlock()
+
+	slices.SortFunc(requests, func(a, b *inflightRequest) int {
+		return a.start.Compare(b.start)
+	})
+
+	now := time.Now()
+	for _, r := range requests {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if r.aborted.Swap(true) {
+			continue
+		}
+
+		attributes := append(
+			m.inflightAttributes(r, now),
+			slog.Bool("aborted", true),
+		)
+
+		m.logger.LogAttrs(r.ctx, m.config.ServerErrorLevel, "Request aborted", attributes...)
+	}
+
+	return nil
+}
+
+func (m *Middleware) trackInflight(r *inflightRequest) {
+	m.inflightMu.Lock()
+	m.inflight[r] = struct{}{}
+	m.inflightMu.Unlock()
+}
+
+func (m *Middleware) untrackInflight(r *inflightRequest) {
+	m.inflightMu.Lock()
+	delete(m.inflight, r)
+	m.inflightMu.Unlock()
+}
+
+// InFlight returns a snapshot of the requests being handled, oldest first.
+// Config.TrackInFlight is always enabled by NewMiddleware.
+func (m *Middleware) InFlight() []InFlightRequest {
+	now := time.Now()
+
+	m.inflightMu.Lock()
+	output := make([]InFlightRequest, 0, len(m.inflight))
+	for r := range m.inflight {
+		output = append(output, r.snapshot(now))
+	}
+	m.inflightMu.Unlock()
+
+	slices.SortFunc(output, func(a, b InFlightRequest) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	return output
+}
+
+// InFlightHandler returns a handler rendering the requests being handled as
+// JSON, for an admin route.
+func (m *Middleware) InFlightHandler() echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		return c.JSON(http.StatusOK, m.InFlight())
+	}
+}
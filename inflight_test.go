@@ -0,0 +1,61 @@
+package slogecho
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v5"
+)
+
+func TestShutdownLogsAbortedRequests(t *testing.T) {
+	var buf syncBuffer
+
+	m := NewMiddleware(slog.New(slog.NewTextHandler(&buf, nil)), DefaultConfig())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	e := echo.New()
+	e.Use(m.Handler)
+	e.GET("/export", func(c *echo.Context) error {
+		close(started)
+		<-release
+		return c.String(200, "done")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/export", nil))
+	}()
+
+	<-started
+	if n := len(m.InFlight()); n != 1 {
+		t.Fatalf("expected 1 request in flight, got: %d", n)
+	}
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("request did not finish")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got: %v", lines)
+	}
+	if !strings.Contains(lines[0], `msg="Request aborted"`) || !strings.Contains(lines[0], "aborted=true") {
+		t.Errorf("expected an aborted record, got: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "response.status=200") || !strings.Contains(lines[1], "aborted=true") {
+		t.Errorf("expected the access record to be marked aborted, got: %s", lines[1])
+	}
+}
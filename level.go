@@ -0,0 +1,80 @@
+package slogecho
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v5"
+)
+
+// LevelResolver returns the level of the log record of a request.
+type LevelResolver func(c *echo.Context, status int, err error, latency time.Duration) slog.Level
+
+// DefaultLevelResolver returns the LevelResolver used when Config.LevelResolver
+// is nil: ClientClosedLevel and DeadlineExceededLevel for client disconnects and
+// expired requests, ServerErrorLevel for 5xx, ClientErrorLevel for 4xx and
+// DefaultLevel otherwise.
+func DefaultLevelResolver(config Config) LevelResolver {
+	return func(c *echo.Context, status int, err error, latency time.Duration) slog.Level {
+		switch {
+		case isClientClosed(status, err):
+			return config.ClientClosedLevel
+		case isDeadlineExceeded(status, err):
+			return config.DeadlineExceededLevel
+		case status >= http.StatusInternalServerError:
+			return config.ServerErrorLevel
+		case status >= http.StatusBadRequest:
+			return config.ClientErrorLevel
+		default:
+			return config.DefaultLevel
+		}
+	}
+}
+
+// StatusLevels returns a LevelResolver mapping status codes to levels.
+// Unknown status codes are resolved by fallback (slog.LevelInfo when nil).
+func StatusLevels(levels map[int]slog.Level, fallback LevelResolver) LevelResolver {
+	return func(c *echo.Context, status int, err error, latency time.Duration) slog.Level {
+		if level, ok := levels[status]; ok {
+			return level
+		}
+
+		return fallbackLevel(fallback, c, status, err, latency)
+	}
+}
+
+// StatusRangeLevel returns a LevelResolver mapping status codes between from
+// and to (inclusive) to a level. Other status codes are resolved by fallback
+// (slog.LevelInfo when nil).
+func StatusRangeLevel(from int, to int, level slog.Level, fallback LevelResolver) LevelResolver {
+	return func(c *echo.Context, status int, err error, latency time.Duration) slog.Level {
+		if status >= from && status <= to {
+			return level
+		}
+
+		return fallbackLevel(fallback, c, status, err, latency)
+	}
+}
+
+// ErrorLevel returns a LevelResolver mapping errors matching target (see
+// errors.Is) to a level, regardless of the status code. Other requests are
+// resolved by fallback (slog.LevelInfo when nil).
+func ErrorLevel(target error, level slog.Level, fallback LevelResolver) LevelResolver {
+	return func(c *echo.Context, status int, err error, latency time.Duration) slog.Level {
+		if err != nil && errors.Is(err, target) {
+			return level
+		}
+
+		return fallbackLevel(fallback, c, status, err, latency)
+	}
+}
+
+func fallbackLevel(fallback LevelResolver, c *echo.Context, status int, err error, latency time.Duration) slog.Level {
+	if fallback == nil {
+		return slog.LevelInfo
+	}
+
+	return fallback(c, status, err, latency)
+}
@@ -0,0 +1,100 @@
+package slogecho
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// messageRecord holds the values available to a message template.
+type messageRecord struct {
+	method         string
+	host           string
+	path           string
+	query          string
+	route          string
+	status         int
+	latency        time.Duration
+	ip             string
+	userAgent      string
+	referer        string
+	requestID      string
+	err            string
+	requestLength  int
+	responseLength int
+}
+
+var messageTemplateTags = map[string]func(r messageRecord) string{
+	"method":          func(r messageRecord) string { return r.method },
+	"host":            func(r messageRecord) string { return r.host },
+	"path":            func(r messageRecord) string { return r.path },
+	"query":           func(r messageRecord) string { return r.query },
+	"route":           func(r messageRecord) string { return r.route },
+	"status":          func(r messageRecord) string { return strconv.Itoa(r.status) },
+	"status_text":     func(r messageRecord) string { return http.StatusText(r.status) },
+	"latency":         func(r messageRecord) string { return r.latency.String() },
+	"latency_ms":      func(r messageRecord) string { return strconv.FormatInt(r.latency.Milliseconds(), 10) },
+	"ip":              func(r messageRecord) string { return r.ip },
+	"user_agent":      func(r messageRecord) string { return r.userAgent },
+	"referer":         func(r messageRecord) string { return r.referer },
+	"id":              func(r messageRecord) string { return r.requestID },
+	"error":           func(r messageRecord) string { return r.err },
+	"request_length":  func(r messageRecord) string { return strconv.Itoa(r.requestLength) },
+	"response_length": func(r messageRecord) string { return strconv.Itoa(r.responseLength) },
+}
+
+type messageSegment struct {
+	literal string
+	tag     func(r messageRecord) string
+}
+
+// messageTemplate is a compiled message template, such as "${method} ${route} ${status} ${latency}".
+type messageTemplate []messageSegment
+
+func parseMessageTemplate(tmpl string) (messageTemplate, error) {
+	output := messageTemplate{}
+
+	for len(tmpl) > 0 {
+		start := strings.Index(tmpl, "${")
+		if start < 0 {
+			output = append(output, messageSegment{literal: tmpl})
+			break
+		}
+
+		if start > 0 {
+			output = append(output, messageSegment{literal: tmpl[:start]})
+		}
+
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("slog-echo: unclosed tag in message template at offset %d", start)
+		}
+
+		name := tmpl[start+2 : start+end]
+		tag, ok := messageTemplateTags[name]
+		if !ok {
+			return nil, fmt.Errorf("slog-echo: unknown tag ${%s} in message template", name)
+		}
+
+		output = append(output, messageSegment{tag: tag})
+		tmpl = tmpl[start+end+1:]
+	}
+
+	return output, nil
+}
+
+func (t messageTemplate) render(r messageRecord) string {
+	var sb strings.Builder
+
+	for _, segment := range t {
+		if segment.tag != nil {
+			sb.WriteString(segment.tag(r))
+		} else {
+			sb.WriteString(segment.literal)
+		}
+	}
+
+	return sb.String()
+}
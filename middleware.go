@@ -1,11 +1,16 @@
 package slogecho
 
 import (
+	"bufio"
 	"context"
+	"crypto/aes"
 	"errors"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/labstack/echo/v5"
@@ -18,13 +23,22 @@ const (
 )
 
 var (
-	TraceIDKey   = "trace_id"
-	SpanIDKey    = "span_id"
-	RequestIDKey = "id"
+	TraceIDKey       = "trace_id"
+	SpanIDKey        = "span_id"
+	RequestIDKey     = "id"
+	CorrelationIDKey = "correlation_id"
 
 	RequestBodyMaxSize  = 64 * 1024 // 64KB
 	ResponseBodyMaxSize = 64 * 1024 // 64KB
 
+	SpoolBodyMaxSize      int64 = 100 * 1024 * 1024 // 100MB
+	BinaryBodyPreviewSize int   = 64
+	DecompressionMaxRatio int   = 100
+
+	// hard limits of Config.WithEagerRequestBody (0 disables multipart requests)
+	EagerRequestBodyMaxSize = 1024 * 1024 // 1MB
+	EagerMultipartMaxSize   = 0
+
 	HiddenRequestHeaders = map[string]struct{}{
 		"authorization": {},
 		"cookie":        {},
@@ -36,23 +50,167 @@ var (
 	HiddenResponseHeaders = map[string]struct{}{
 		"set-cookie": {},
 	}
+
+	// multipart summary (see Config.WithMultipartSummary)
+	MultipartMaxParts     = 64
+	MultipartFieldMaxSize = 1024 // 1KB
+	RedactedFormFields    = map[string]struct{}{
+		"password":      {},
+		"token":         {},
+		"secret":        {},
+		"api_key":       {},
+		"access_token":  {},
+		"refresh_token": {},
+		"client_secret": {},
+	}
 )
 
 type Config struct {
 	DefaultLevel     slog.Level
 	ClientErrorLevel slog.Level
 	ServerErrorLevel slog.Level
-
-	WithUserAgent      bool
-	WithRequestID      bool
-	WithRequestBody    bool
-	WithRequestHeader  bool
-	WithResponseBody   bool
-	WithResponseHeader bool
-	WithSpanID         bool
-	WithTraceID        bool
-	WithClientIP       bool
-	WithCustomMessage  func(c *echo.Context, err error) string
+	// ClientClosedLevel is used when the client went away before the end of the request (status 499).
+	ClientClosedLevel slog.Level
+	// DeadlineExceededLevel is used when the request context expired (status 504).
+	DeadlineExceededLevel slog.Level
+	// LevelResolver overrides DefaultLevel, ClientErrorLevel and ServerErrorLevel.
+	LevelResolver LevelResolver
+
+	WithUserAgent   bool
+	WithRequestID   bool
+	WithRequestBody bool
+	// WithEagerRequestBody reads the request body (see WithRequestBody) before
+	// calling the handler, so that it is logged even when the handler does not
+	// read it. The handler reads the buffered prefix, then the rest of the body.
+	// See EagerRequestBodyMaxSize and EagerMultipartMaxSize.
+	WithEagerRequestBody bool
+	WithRequestHeader    bool
+	WithResponseBody     bool
+	WithResponseHeader   bool
+	WithSpanID           bool
+	WithTraceID          bool
+	WithClientIP         bool
+	// WithResponseTimings logs the time to first byte (headers written), the
+	// duration of the handler and the time spent writing the response body.
+	WithResponseTimings bool
+	// WithThroughput logs the transfer rate of the request and response bodies
+	// (bytes per second, between the first and the end of the last read/write),
+	// and the time the handler spent blocked reading the request body.
+	WithThroughput bool
+	// WithContentLength logs the declared request Content-Length and whether the
+	// request body has been drained by the handler. Responses whose length
+	// disagrees with their Content-Length header are flagged.
+	WithContentLength bool
+	// WithTimings logs the timers of StartTimer and Observe as a "timings" group.
+	// WithServerTimingHeader sends them in a Server-Timing response header,
+	// when the headers are written.
+	WithTimings            bool
+	WithServerTimingHeader bool
+	WithCustomMessage      func(c *echo.Context, err error) string
+	// MessageTemplate builds the log message from the request, eg: "${method} ${route} ${status} ${latency}".
+	// Available tags: method, host, path, query, route, status, status_text, latency, latency_ms,
+	// ip, user_agent, referer, id, error, request_length, response_length.
+	// WithCustomMessage takes precedence over MessageTemplate.
+	MessageTemplate string
+
+	// ErrorClassifier extracts the status, code and public message of errors.
+	// Unknown errors are classified using the StatusCode() and ErrorCode()
+	// methods found in the error chain.
+	ErrorClassifier ErrorClassifier
+
+	// WithErrorFingerprint logs a stable "error.fingerprint", computed from the route
+	// template, the error types and the error message normalized by ErrorMessageNormalizer
+	// (NormalizeErrorMessage when nil).
+	WithErrorFingerprint   bool
+	ErrorMessageNormalizer func(msg string) string
+	// WithRecover recovers panics of the handler, converts them into 500 errors
+	// and logs the panic value and stack in the same record.
+	WithRecover bool
+	// WithStackTrace logs the stack trace carried by errors: pkg/errors-style
+	// `StackTrace()` or `Callers() []uintptr`.
+	WithStackTrace bool
+
+	// DedupWindow enables the deduplication of server errors (5xx) sharing the same
+	// route, status and error fingerprint: within the window, the first DedupBurst records
+	// are logged, then a summary of the suppressed records is logged when the window
+	// closes (or on Middleware.Shutdown).
+	DedupWindow time.Duration
+	DedupBurst  int
+
+	// WithRequestStart logs a "Request started" record before calling the handler,
+	// at RequestStartLevel, for requests accepted by RequestStartFilters (the error
+	// is always nil). Both records share a correlation id (see CorrelationIDKey).
+	WithRequestStart    bool
+	RequestStartLevel   slog.Level
+	RequestStartFilters []Filter
+
+	// WithHijackedConnections logs a record when a hijacked connection (eg: WebSocket)
+	// is closed, with the session duration, bytes in/out and close reason.
+	// WithWebSocketFrames also counts the WebSocket frames and messages.
+	WithHijackedConnections bool
+	WithWebSocketFrames     bool
+
+	// Streaming responses (text/event-stream or flushed) are logged with a "stream"
+	// group: flush count, server-sent event count and stream duration. Their body
+	// is not captured, unless WithStreamingResponseBody. StreamProgressInterval
+	// logs a "Stream in progress" record at StreamProgressLevel while streaming.
+	WithStreamingResponseBody bool
+	StreamProgressInterval    time.Duration
+	StreamProgressLevel       slog.Level
+
+	// BodyContentTypes and BodyExcludedContentTypes are MIME patterns (eg: "application/json",
+	// "image/*") of the bodies captured as text. Other bodies, compressed bodies and
+	// bodies sniffed as binary are logged in body_binary using BinaryBodyFormat
+	// (BinaryBodyPreviewSize bytes at most). An empty BinaryBodyFormat disables the
+	// binary sniffer.
+	BodyContentTypes         []string
+	BodyExcludedContentTypes []string
+	BinaryBodyFormat         BinaryBodyFormat
+	// WithDecompression decodes the captured bodies with a Content-Encoding found in
+	// Decompressors (DefaultDecompressors when nil: gzip, deflate, br and zstd), up to
+	// the capture limit and DecompressionMaxRatio, and logs the captured and decoded
+	// sizes.
+	WithDecompression bool
+	Decompressors     map[string]Decompressor
+
+	// WithMultipartSummary logs a summary of multipart/form-data request bodies,
+	// instead of the raw body: the form fields (see RedactedFormFields) and, for
+	// each file, the field name, filename, declared and sniffed content types
+	// and size. The summary covers the parts read by the handler.
+	WithMultipartSummary bool
+
+	// BodySpooler spools the captured bodies larger than RequestBodyMaxSize and
+	// ResponseBodyMaxSize (up to SpoolBodyMaxSize), eg: a DirSpooler.
+	// The record has the size, the sha256 hash and the reference of the spooled
+	// body, instead of the truncated body. SpoolGzip compresses the spooled bodies,
+	// SpoolEncryptionKey encrypts them (AES-GCM, with a key derived per body), see
+	// OpenSpooledBody.
+	BodySpooler        BodySpooler
+	SpoolGzip          bool
+	SpoolEncryptionKey []byte
+
+	// TrackInFlight maintains a registry of the requests being handled, see
+	// Middleware.InFlight, Middleware.InFlightHandler and Middleware.Shutdown.
+	// It is always enabled by NewMiddleware.
+	TrackInFlight bool
+
+	// WatchdogThreshold enables a "request still running" record, logged at
+	// WatchdogLevel once a request lasts longer than the threshold, then every
+	// WatchdogInterval (if positive).
+	WatchdogThreshold time.Duration
+	WatchdogInterval  time.Duration
+	WatchdogLevel     slog.Level
+
+	// HandleError calls the Echo HTTPErrorHandler before logging and returns the
+	// error of the handler unchanged. The logged status is the one sent to the client.
+	HandleError bool
+
+	// SlowRequestThresholds raise the level of slow requests. The highest
+	// threshold reached by the request wins. RouteSlowRequestThresholds
+	// overrides the global thresholds for a given route template (eg: "/users/:id").
+	SlowRequestThresholds      []SlowRequestThreshold
+	RouteSlowRequestThresholds map[string][]SlowRequestThreshold
+	SlowRequestMessage         string
 
 	Filters []Filter
 }
@@ -78,238 +236,646 @@ func NewWithFilters(logger *slog.Logger, filters ...Filter) echo.MiddlewareFunc
 // DefaultConfig returns the default configuration for the request logger.
 func DefaultConfig() Config {
 	return Config{
-		DefaultLevel:     slog.LevelInfo,
-		ClientErrorLevel: slog.LevelWarn,
-		ServerErrorLevel: slog.LevelError,
-
-		WithUserAgent:      false,
-		WithRequestID:      true,
-		WithRequestBody:    false,
-		WithRequestHeader:  false,
-		WithResponseBody:   false,
-		WithResponseHeader: false,
-		WithSpanID:         false,
-		WithTraceID:        false,
-		WithClientIP:       true,
-		WithCustomMessage:  nil,
+		DefaultLevel:          slog.LevelInfo,
+		ClientErrorLevel:      slog.LevelWarn,
+		ServerErrorLevel:      slog.LevelError,
+		ClientClosedLevel:     slog.LevelInfo,
+		DeadlineExceededLevel: slog.LevelWarn,
+		LevelResolver:         nil,
+
+		WithUserAgent:          false,
+		WithRequestID:          true,
+		WithRequestBody:        false,
+		WithRequestHeader:      false,
+		WithResponseBody:       false,
+		WithResponseHeader:     false,
+		WithSpanID:             false,
+		WithTraceID:            false,
+		WithClientIP:           true,
+		WithResponseTimings:    false,
+		WithThroughput:         false,
+		WithContentLength:      false,
+		WithTimings:            false,
+		WithServerTimingHeader: false,
+		WithCustomMessage:      nil,
+		MessageTemplate:        "",
+
+		ErrorClassifier: nil,
+
+		WithErrorFingerprint:   false,
+		ErrorMessageNormalizer: nil,
+		WithRecover:            false,
+		WithStackTrace:         false,
+
+		DedupWindow: 0,
+		DedupBurst:  0,
+
+		WithRequestStart:    false,
+		RequestStartLevel:   slog.LevelInfo,
+		RequestStartFilters: []Filter{},
+
+		WithHijackedConnections: false,
+		WithWebSocketFrames:     false,
+
+		WithStreamingResponseBody: false,
+		StreamProgressInterval:    0,
+		StreamProgressLevel:       slog.LevelInfo,
+
+		BodyContentTypes:         nil,
+		BodyExcludedContentTypes: nil,
+		BinaryBodyFormat:         "",
+		WithDecompression:        false,
+		Decompressors:            nil,
+
+		WithMultipartSummary: false,
+
+		BodySpooler:        nil,
+		SpoolGzip:          false,
+		SpoolEncryptionKey: nil,
+
+		TrackInFlight: false,
+
+		WatchdogThreshold: 0,
+		WatchdogInterval:  0,
+		WatchdogLevel:     slog.LevelWarn,
+
+		HandleError: false,
+
+		SlowRequestThresholds:      []SlowRequestThreshold{},
+		RouteSlowRequestThresholds: map[string][]SlowRequestThreshold{},
+		SlowRequestMessage:         "",
 
 		Filters: []Filter{},
 	}
 }
 
+// Middleware is a handle on a request logger. Handler is the echo.MiddlewareFunc.
+type Middleware struct {
+	logger *slog.Logger
+	config Config
+
+	resolveLevel LevelResolver
+	msgTemplate  messageTemplate
+	dedup        *deduplicator
+
+	inflightMu sync.Mutex
+	inflight   map[*inflightRequest]struct{}
+}
+
 // NewWithConfig returns a echo.HandlerFunc (middleware) that logs requests using slog.
+//
+// It panics if config.MessageTemplate is invalid.
 func NewWithConfig(logger *slog.Logger, config Config) echo.MiddlewareFunc {
-	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c *echo.Context) (err error) {
-			req := c.Request()
-			start := time.Now()
-			path := req.URL.Path
-			query := req.URL.RawQuery
-
-			params := map[string]string{}
-			for _, p := range c.PathValues() {
-				params[p.Name] = p.Value
+	return newMiddleware(logger, config).Handler
+}
+
+// NewMiddleware returns a handle on a request logger. Unlike NewWithConfig, the
+// handle can be shut down, to flush the pending records, and tracks the
+// requests being handled (see Config.TrackInFlight).
+//
+//	m := slogecho.NewMiddleware(logger, config)
+//	e.Use(m.Handler)
+//	defer m.Shutdown(context.Background())
+//
+// It panics if config.MessageTemplate is invalid.
+func NewMiddleware(logger *slog.Logger, config Config) *Middleware {
+	config.TrackInFlight = true
+	return newMiddleware(logger, config)
+}
+
+func newMiddleware(logger *slog.Logger, config Config) *Middleware {
+	var msgTemplate messageTemplate
+	if config.MessageTemplate != "" {
+		var err error
+		msgTemplate, err = parseMessageTemplate(config.MessageTemplate)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if config.SpoolEncryptionKey != nil {
+		if _, err := aes.NewCipher(config.SpoolEncryptionKey); err != nil {
+			panic(err)
+		}
+	}
+
+	resolveLevel := config.LevelResolver
+	if resolveLevel == nil {
+		resolveLevel = DefaultLevelResolver(config)
+	}
+
+	var dedup *deduplicator
+	if config.DedupWindow > 0 {
+		dedup = newDeduplicator(logger, config.DedupWindow, config.DedupBurst)
+	}
+
+	return &Middleware{
+		logger: logger,
+		config: config,
+
+		resolveLevel: resolveLevel,
+		msgTemplate:  msgTemplate,
+		dedup:        dedup,
+
+		inflight: map[*inflightRequest]struct{}{},
+	}
+}
+
+// Handler is the echo.MiddlewareFunc logging requests.
+func (m *Middleware) Handler(next echo.HandlerFunc) echo.HandlerFunc {
+	config := m.config
+
+	return func(c *echo.Context) (err error) {
+		req := c.Request()
+		start := time.Now()
+		path := req.URL.Path
+		query := req.URL.RawQuery
+
+		params := map[string]string{}
+		for _, p := range c.PathValues() {
+			params[p.Name] = p.Value
+		}
+
+		// multipart forms are summarized instead of captured
+		var multipart *multipartSummary
+		if config.WithMultipartSummary {
+			if boundary := multipartBoundary(req); boundary != "" {
+				multipart = newMultipartSummary(boundary)
+				// stops the parser when the handler panics
+				defer multipart.finish()
 			}
+		}
+		recordRequestBody := config.WithRequestBody && multipart == nil
 
-			// dump request body
-			br := newBodyReader(req.Body, RequestBodyMaxSize, config.WithRequestBody)
-			req.Body = br
+		// dump request body
+		var eagerBody []byte
+		if recordRequestBody && config.WithEagerRequestBody {
+			eagerBody = eagerReadBody(req, min(RequestBodyMaxSize, EagerRequestBodyMaxSize))
+		}
 
-			// dump response body
-			bw := newBodyWriter(c.Response(), ResponseBodyMaxSize, config.WithResponseBody)
-			c.SetResponse(bw)
+		br := newBodyReader(req.Body, RequestBodyMaxSize, recordRequestBody)
+		if eagerBody != nil {
+			// the handler reads the eager prefix again
+			br.body.Write(eagerBody)
+			br.skip = len(eagerBody)
+		}
+		if multipart != nil {
+			br.tee = multipart
+		}
+		req.Body = br
+
+		// dump response body
+		bw := newBodyWriter(c.Response(), ResponseBodyMaxSize, config.WithResponseBody, config.WithStreamingResponseBody)
+		c.SetResponse(bw)
+
+		// spool the bodies larger than the inline limits
+		if config.BodySpooler != nil {
+			br.openSpool = func() *spool { return m.openSpool(c, "request") }
+			bw.openSpool = func() *spool { return m.openSpool(c, "response") }
+			// closes the spools when the handler panics
+			defer closeSpools(br, bw)
+		}
 
-			err = next(c)
+		// request-scoped timers, created before the handler for concurrent use
+		var reqTimings *timings
+		if config.WithTimings || config.WithServerTimingHeader {
+			reqTimings = getTimings(c)
+		}
 
-			if err != nil {
-				if _, ok := err.(*echo.HTTPError); !ok {
-					err = echo.NewHTTPError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)).Wrap(err)
-				}
+		if config.WithServerTimingHeader {
+			if resp, _ := echo.UnwrapResponse(bw); resp != nil {
+				resp.Before(func() {
+					if value := reqTimings.serverTiming(); value != "" {
+						resp.Header().Add("Server-Timing", value)
+					}
+				})
+			}
+		}
+
+		inflight := newInflightRequest(c, start, br, bw)
+		if config.TrackInFlight {
+			m.trackInflight(inflight)
+			defer m.untrackInflight(inflight)
+		}
+
+		// two-phase logging: both records share a correlation id
+		correlationID := ""
+		if config.WithRequestStart {
+			correlationID = m.logRequestStart(c, start, params)
+		}
+
+		if config.WithHijackedConnections {
+			bw.onHijack = func(conn net.Conn, rw *bufio.ReadWriter) (net.Conn, *bufio.ReadWriter) {
+				// inner middlewares (eg: middleware.RequestID) set the request id
+				// once the request has started
+				requestID := getRequestID(c)
+				return m.wrapHijackedConn(conn, rw, func(hc *hijackedConn) {
+					m.logHijackedConn(inflight, requestID, correlationID, hc)
+				})
 			}
+		}
 
-			// Pass thru filters and skip early the code below, to prevent unnecessary processing.
-			for _, filter := range config.Filters {
-				if !filter(c, err) {
-					return
+		var progress *streamProgress
+		if config.StreamProgressInterval > 0 {
+			bw.onStream = func() {
+				progress = m.startStreamProgress(inflight)
+			}
+			// stops the progress records when the handler panics
+			defer func() {
+				if progress != nil {
+					progress.stop()
 				}
+			}()
+		}
+
+		var wd *watchdog
+		if config.WatchdogThreshold > 0 {
+			wd = m.startWatchdog(inflight)
+			// stops the watchdog when the handler panics
+			defer wd.stop()
+		}
+
+		err = callNext(next, c, config.WithRecover)
+		handlerEnd := time.Now()
+
+		watchdogWarnings := 0
+		if wd != nil {
+			watchdogWarnings = wd.stop()
+		}
+
+		if progress != nil {
+			progress.stop()
+		}
+
+		if multipart != nil {
+			multipart.finish()
+		}
+
+		// logErr is the error as seen by the logger.
+		// inner middlewares may have replaced the request context
+		logErr, disconnected := classifyDisconnect(req.Context(), c.Request().Context(), err)
+
+		var errClassification ErrorClassification
+		if logErr != nil {
+			errClassification = config.classifyError(c, logErr)
+			if _, ok := logErr.(*echo.HTTPError); !ok {
+				logErr = newHTTPError(logErr, errClassification)
 			}
+		}
 
-			_, status := echo.ResolveResponseStatus(c.Response(), err)
-			method := req.Method
-			host := req.Host
-			route := c.Path()
-			end := time.Now()
-			latency := end.Sub(start)
-			userAgent := req.UserAgent()
-			ip := c.RealIP()
-			referer := c.Request().Referer()
-
-			errMsg := ""
-
-			var httpErr *echo.HTTPError
-			if err != nil && errors.As(err, &httpErr) {
-				errMsg = httpErr.Message
+		if config.HandleError {
+			// The error handler writes the response now, so that the logged status,
+			// size and headers are the ones received by the client. The original
+			// error is returned unchanged: the error handler is expected to skip
+			// committed responses.
+			if err != nil {
+				c.Echo().HTTPErrorHandler(c, err)
 			}
+		} else {
+			err = logErr
+		}
 
-			baseAttributes := make([]slog.Attr, 0, 3)
-			requestAttributes := make([]slog.Attr, 0, 14)
-			responseAttributes := make([]slog.Attr, 0, 6)
+		// after the error handler, which may write the response
+		closeSpools(br, bw)
 
+		// Pass thru filters and skip early the code below, to prevent unnecessary processing.
+		for _, filter := range config.Filters {
+			if !filter(c, logErr) {
+				return
+			}
+		}
+
+		_, status := echo.ResolveResponseStatus(c.Response(), logErr)
+		if disconnected != nil {
+			// the client did not receive the response, whatever has been committed
+			status = echo.StatusCode(logErr)
+		}
+		method := req.Method
+		host := req.Host
+		route := c.Path()
+		end := time.Now()
+		latency := end.Sub(start)
+		userAgent := req.UserAgent()
+		ip := c.RealIP()
+		referer := c.Request().Referer()
+
+		errMsg := ""
+
+		var httpErr *echo.HTTPError
+		if logErr != nil && errors.As(logErr, &httpErr) {
+			errMsg = httpErr.Message
+		}
+
+		baseAttributes := make([]slog.Attr, 0, 3)
+		requestAttributes := make([]slog.Attr, 0, 14)
+		responseAttributes := make([]slog.Attr, 0, 6)
+
+		requestAttributes = append(requestAttributes,
+			slog.Time("time", start.UTC()),
+			slog.String("method", method),
+			slog.String("host", host),
+			slog.String("path", path),
+			slog.String("query", query),
+			slog.Any("params", params),
+			slog.String("route", route),
+			slog.String("referer", referer),
+		)
+
+		if config.WithClientIP {
 			requestAttributes = append(requestAttributes,
-				slog.Time("time", start.UTC()),
-				slog.String("method", method),
-				slog.String("host", host),
-				slog.String("path", path),
-				slog.String("query", query),
-				slog.Any("params", params),
-				slog.String("route", route),
-				slog.String("referer", referer),
+				slog.String("ip", ip),
 			)
+		}
 
-			if config.WithClientIP {
-				requestAttributes = append(requestAttributes,
-					slog.String("ip", ip),
-				)
-			}
+		responseAttributes = append(responseAttributes,
+			slog.Time("time", end.UTC()),
+			slog.Duration("latency", latency),
+			slog.Int("status", status),
+		)
 
-			responseAttributes = append(responseAttributes,
-				slog.Time("time", end.UTC()),
-				slog.Duration("latency", latency),
-				slog.Int("status", status),
+		requestID := getRequestID(c)
+		if config.WithRequestID && requestID != "" {
+			baseAttributes = append(baseAttributes, slog.String(RequestIDKey, requestID))
+		}
+
+		if correlationID != "" {
+			baseAttributes = append(baseAttributes, slog.String(CorrelationIDKey, correlationID))
+		}
+
+		if watchdogWarnings > 0 {
+			baseAttributes = append(baseAttributes, slog.Int("watchdog_warnings", watchdogWarnings))
+		}
+
+		// already logged as "Request aborted" by Shutdown
+		if inflight.aborted.Load() {
+			baseAttributes = append(baseAttributes, slog.Bool("aborted", true))
+		}
+
+		if disconnected != nil {
+			baseAttributes = append(
+				baseAttributes,
+				slog.Group("disconnect", disconnected.attrs(bw.bytes.Load())...),
 			)
+		}
 
-			if config.WithRequestID {
-				requestID := req.Header.Get(echo.HeaderXRequestID)
-				if requestID == "" {
-					requestID = c.Response().Header().Get(echo.HeaderXRequestID)
-				}
-				if requestID != "" {
-					baseAttributes = append(baseAttributes, slog.String(RequestIDKey, requestID))
+		// otel
+		baseAttributes = append(baseAttributes, extractTraceSpanID(c.Request().Context(), config.WithTraceID, config.WithSpanID)...)
+
+		// request body
+		requestAttributes = append(requestAttributes, slog.Int("length", int(br.bytes.Load())))
+		if multipart != nil {
+			requestAttributes = append(requestAttributes, slog.Group("multipart", multipart.attrs()...))
+		} else if config.WithRequestBody {
+			requestAttributes = append(requestAttributes, config.bodyAttributes(br.body, br.spool, req.Header, RequestBodyMaxSize)...)
+		}
+
+		// request content length
+		if config.WithContentLength {
+			requestAttributes = append(
+				requestAttributes,
+				slog.Int64("content_length", req.ContentLength),
+				slog.Bool("drained", br.drained(req.ContentLength)),
+			)
+		}
+
+		// request throughput
+		if config.WithThroughput {
+			requestAttributes = append(
+				requestAttributes,
+				slog.Float64("bytes_per_second", throughput(br.bytes.Load(), br.readDuration())),
+				slog.Duration("read_blocked", br.blocked),
+			)
+		}
+
+		// request headers
+		if config.WithRequestHeader {
+			kv := []any{}
+
+			for k, v := range c.Request().Header {
+				if _, found := HiddenRequestHeaders[strings.ToLower(k)]; found {
+					continue
 				}
+				kv = append(kv, slog.Any(k, v))
 			}
 
-			// otel
-			baseAttributes = append(baseAttributes, extractTraceSpanID(c.Request().Context(), config.WithTraceID, config.WithSpanID)...)
+			requestAttributes = append(requestAttributes, slog.Group("header", kv...))
+		}
+
+		if config.WithUserAgent {
+			requestAttributes = append(requestAttributes, slog.String("user-agent", userAgent))
+		}
+
+		xForwardedFor, ok := c.Get(echo.HeaderXForwardedFor).(string)
+		if ok && len(xForwardedFor) > 0 {
+			ips := lo.Map(strings.Split(xForwardedFor, ","), func(ip string, _ int) string {
+				return strings.TrimSpace(ip)
+			})
+			requestAttributes = append(requestAttributes, slog.Any("x-forwarded-for", ips))
+		}
 
-			// request body
-			requestAttributes = append(requestAttributes, slog.Int("length", br.bytes))
-			if config.WithRequestBody {
-				requestAttributes = append(requestAttributes, slog.String("body", br.body.String()))
+		// response timings
+		if config.WithResponseTimings {
+			if !bw.headerAt.IsZero() {
+				responseAttributes = append(responseAttributes, slog.Duration("ttfb", bw.headerAt.Sub(start)))
 			}
 
-			// request headers
-			if config.WithRequestHeader {
-				kv := []any{}
+			responseAttributes = append(
+				responseAttributes,
+				slog.Duration("handler_duration", handlerEnd.Sub(start)),
+				slog.Duration("write_duration", bw.writeDuration()),
+			)
+		}
 
-				for k, v := range c.Request().Header {
-					if _, found := HiddenRequestHeaders[strings.ToLower(k)]; found {
-						continue
-					}
-					kv = append(kv, slog.Any(k, v))
-				}
+		// streaming response
+		if bw.streaming.Load() {
+			responseAttributes = append(
+				responseAttributes,
+				slog.Group(
+					"stream",
+					append(streamAttributes(bw), slog.Duration("duration", handlerEnd.Sub(bw.streamAt)))...,
+				),
+			)
+		}
+
+		// response body
+		responseAttributes = append(responseAttributes, slog.Int("length", int(bw.bytes.Load())))
+		if config.WithResponseBody && (bw.body != nil || bw.spool != nil) {
+			responseAttributes = append(responseAttributes, config.bodyAttributes(bw.body, bw.spool, c.Response().Header(), ResponseBodyMaxSize)...)
+		}
 
-				requestAttributes = append(requestAttributes, slog.Group("header", kv...))
+		// response content length
+		if config.WithContentLength {
+			if declared, ok := responseContentLength(req, c.Response(), status); ok && declared != bw.bytes.Load() {
+				responseAttributes = append(
+					responseAttributes,
+					slog.Int64("content_length", declared),
+					slog.Bool("content_length_mismatch", true),
+				)
 			}
+		}
 
-			if config.WithUserAgent {
-				requestAttributes = append(requestAttributes, slog.String("user-agent", userAgent))
+		// response throughput
+		if config.WithThroughput {
+			responseAttributes = append(
+				responseAttributes,
+				slog.Float64("bytes_per_second", throughput(bw.bytes.Load(), bw.writeDuration())),
+			)
+		}
+
+		// response headers
+		if config.WithResponseHeader {
+			kv := []any{}
+
+			for k, v := range c.Response().Header() {
+				if _, found := HiddenResponseHeaders[strings.ToLower(k)]; found {
+					continue
+				}
+				kv = append(kv, slog.Any(k, v))
 			}
 
-			xForwardedFor, ok := c.Get(echo.HeaderXForwardedFor).(string)
-			if ok && len(xForwardedFor) > 0 {
-				ips := lo.Map(strings.Split(xForwardedFor, ","), func(ip string, _ int) string {
-					return strings.TrimSpace(ip)
-				})
-				requestAttributes = append(requestAttributes, slog.Any("x-forwarded-for", ips))
+			responseAttributes = append(responseAttributes, slog.Group("header", kv...))
+		}
+
+		attributes := append(
+			[]slog.Attr{
+				{
+					Key:   "request",
+					Value: slog.GroupValue(requestAttributes...),
+				},
+				{
+					Key:   "response",
+					Value: slog.GroupValue(responseAttributes...),
+				},
+			},
+			baseAttributes...,
+		)
+
+		if config.WithTimings {
+			if kv := reqTimings.attrs(); len(kv) > 0 {
+				attributes = append(attributes, slog.Group("timings", kv...))
 			}
+		}
 
-			// response body
-			responseAttributes = append(responseAttributes, slog.Int("length", bw.bytes))
-			if config.WithResponseBody {
-				responseAttributes = append(responseAttributes, slog.String("body", bw.body.String()))
+		// custom context values
+		if v := c.Get(customAttributesCtxKey); v != nil {
+			switch attrs := v.(type) {
+			case []slog.Attr:
+				attributes = append(attributes, attrs...)
 			}
+		}
 
-			// response headers
-			if config.WithResponseHeader {
-				kv := []any{}
+		level := m.resolveLevel(c, status, logErr, latency)
+		msg := "Incoming request"
 
-				for k, v := range c.Response().Header() {
-					if _, found := HiddenResponseHeaders[strings.ToLower(k)]; found {
-						continue
-					}
-					kv = append(kv, slog.Any(k, v))
-				}
+		if status >= http.StatusBadRequest {
+			if logErr != nil {
+				msg = errMsg
+			} else {
+				msg = http.StatusText(status)
+			}
+		}
+
+		// slow requests are escalated, but never below the level of an error
+		if threshold, ok := config.slowRequestThreshold(route, latency); ok {
+			attributes = append(attributes, slog.Bool("slow", true))
 
-				responseAttributes = append(responseAttributes, slog.Group("header", kv...))
+			if threshold.Level > level {
+				level = threshold.Level
 			}
 
-			attributes := append(
-				[]slog.Attr{
-					{
-						Key:   "request",
-						Value: slog.GroupValue(requestAttributes...),
-					},
-					{
-						Key:   "response",
-						Value: slog.GroupValue(responseAttributes...),
-					},
-				},
-				baseAttributes...,
-			)
+			if status < http.StatusBadRequest && config.SlowRequestMessage != "" {
+				msg = config.SlowRequestMessage
+			}
+		}
 
-			// custom context values
-			if v := c.Get(customAttributesCtxKey); v != nil {
-				switch attrs := v.(type) {
-				case []slog.Attr:
-					attributes = append(attributes, attrs...)
-				}
+		fingerprint := ""
+		if httpErr != nil {
+			errAttributes := map[string]any{
+				"code":     httpErr.Code,
+				"message":  httpErr.Message,
+				"internal": httpErr.Unwrap(),
+			}
+			if errClassification.Code != "" {
+				errAttributes["reason"] = errClassification.Code
+			}
+			if config.WithErrorFingerprint || m.dedup != nil {
+				fingerprint = errorFingerprint(route, httpErr, config.ErrorMessageNormalizer)
+			}
+			if config.WithErrorFingerprint {
+				errAttributes["fingerprint"] = fingerprint
 			}
 
-			level := config.DefaultLevel
-			msg := "Incoming request"
+			attributes = append(attributes, slog.Any("error", errAttributes))
 
-			if status >= http.StatusInternalServerError {
-				level = config.ServerErrorLevel
-				if err != nil {
-					msg = errMsg
-				} else {
-					msg = http.StatusText(status)
-				}
-			} else if status >= http.StatusBadRequest && status < http.StatusInternalServerError {
-				level = config.ClientErrorLevel
-				if err != nil {
-					msg = errMsg
-				} else {
-					msg = http.StatusText(status)
-				}
+			if httpErr.Unwrap() != nil {
+				attributes = append(attributes, slog.Any("internal", errorChain(httpErr.Unwrap())))
 			}
 
-			if httpErr != nil {
+			if pe, ok := findPanicError(httpErr); ok {
 				attributes = append(
 					attributes,
-					slog.Any("error", map[string]any{
-						"code":     httpErr.Code,
-						"message":  httpErr.Message,
-						"internal": httpErr.Unwrap(),
-					}),
+					slog.Group(
+						"panic",
+						slog.String("value", fmt.Sprint(pe.value)),
+						slog.Any("stack", formatStack(pe.pcs)),
+					),
 				)
-
-				if httpErr.Unwrap() != nil {
-					attributes = append(attributes, slog.String("internal", httpErr.Unwrap().Error()))
+			} else if config.WithStackTrace {
+				if stack := errorStackTrace(httpErr); len(stack) > 0 {
+					attributes = append(attributes, slog.Any("stacktrace", stack))
 				}
 			}
+		}
 
-			if config.WithCustomMessage != nil {
-				msg = config.WithCustomMessage(c, err)
-			}
+		if m.msgTemplate != nil {
+			msg = m.msgTemplate.render(messageRecord{
+				method:         method,
+				host:           host,
+				path:           path,
+				query:          query,
+				route:          route,
+				status:         status,
+				latency:        latency,
+				ip:             ip,
+				userAgent:      userAgent,
+				referer:        referer,
+				requestID:      requestID,
+				err:            errMsg,
+				requestLength:  int(br.bytes.Load()),
+				responseLength: int(bw.bytes.Load()),
+			})
+		}
 
-			logger.LogAttrs(c.Request().Context(), level, msg, attributes...)
+		if config.WithCustomMessage != nil {
+			msg = config.WithCustomMessage(c, logErr)
+		}
 
-			return
+		// repeated server errors are suppressed, and summarized at the end of the window
+		if m.dedup != nil && httpErr != nil && status >= http.StatusInternalServerError {
+			key := dedupKey{route: route, status: status, fingerprint: fingerprint}
+			if !m.dedup.allow(key, level, msg, end, latency) {
+				return
+			}
 		}
+
+		m.logger.LogAttrs(c.Request().Context(), level, msg, attributes...)
+
+		return
 	}
 }
 
+// Shutdown logs the requests still running with an "aborted" attribute and
+// flushes the pending records: summaries of deduplicated records. It is meant
+// to be called after the graceful shutdown of the server. The requests
+// finishing afterwards are logged with an "aborted" attribute too.
+func (m *Middleware) Shutdown(ctx context.Context) error {
+	if m.dedup != nil {
+		m.dedup.flushAll()
+	}
+
+	return m.logAborted(ctx)
+}
+
 // AddCustomAttributes adds custom attributes to the request context.
 func AddCustomAttributes(c *echo.Context, attrs ...slog.Attr) {
 	v := c.Get(customAttributesCtxKey)
@@ -324,6 +890,14 @@ func AddCustomAttributes(c *echo.Context, attrs ...slog.Attr) {
 	}
 }
 
+func getRequestID(c *echo.Context) string {
+	requestID := c.Request().Header.Get(echo.HeaderXRequestID)
+	if requestID == "" {
+		requestID = c.Response().Header().Get(echo.HeaderXRequestID)
+	}
+	return requestID
+}
+
 func extractTraceSpanID(ctx context.Context, withTraceID bool, withSpanID bool) []slog.Attr {
 	if !withTraceID && !withSpanID {
 		return []slog.Attr{}
@@ -0,0 +1,170 @@
+package slogecho
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var errMultipartDone = errors.New("multipart summary done")
+
+type multipartFile struct {
+	field       string
+	filename    string
+	contentType string
+	sniffedType string
+	size        int64
+}
+
+// multipartSummary parses the multipart/form-data body read by the handler
+// (see bodyReader.tee) without retaining the file contents.
+type multipartSummary struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+
+	// written by the parser, read once done
+	fields    map[string][]string
+	files     []multipartFile
+	truncated bool
+	err       error
+}
+
+// multipartBoundary returns the boundary of a multipart/form-data request.
+func multipartBoundary(req *http.Request) string {
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return ""
+	}
+	return params["boundary"]
+}
+
+func newMultipartSummary(boundary string) *multipartSummary {
+	pr, pw := io.Pipe()
+
+	s := &multipartSummary{
+		pw:     pw,
+		done:   make(chan struct{}),
+		fields: map[string][]string{},
+	}
+
+	go func() {
+		defer close(s.done)
+
+		s.parse(multipart.NewReader(pr, boundary))
+
+		// unblock the writes of the remaining bytes
+		_ = pr.CloseWithError(errMultipartDone)
+	}()
+
+	return s
+}
+
+// implements io.Writer
+func (s *multipartSummary) Write(b []byte) (int, error) {
+	// errors are reported by the parser
+	_, _ = s.pw.Write(b)
+	return len(b), nil
+}
+
+func (s *multipartSummary) parse(mr *multipart.Reader) {
+	for parts := 0; ; parts++ {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			s.err = err
+			return
+		}
+
+		if parts >= MultipartMaxParts {
+			s.truncated = true
+			_, _ = io.Copy(io.Discard, part)
+			continue
+		}
+
+		if part.FileName() == "" {
+			s.parseField(part)
+		} else {
+			s.parseFile(part)
+		}
+	}
+}
+
+func (s *multipartSummary) parseField(part *multipart.Part) {
+	value, _ := io.ReadAll(io.LimitReader(part, int64(MultipartFieldMaxSize)))
+	_, _ = io.Copy(io.Discard, part)
+
+	name := part.FormName()
+	if _, found := RedactedFormFields[strings.ToLower(name)]; found {
+		value = []byte("[REDACTED]")
+	}
+
+	s.fields[name] = append(s.fields[name], string(value))
+}
+
+func (s *multipartSummary) parseFile(part *multipart.Part) {
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(part, head)
+	rest, _ := io.Copy(io.Discard, part)
+
+	s.files = append(s.files, multipartFile{
+		field:       part.FormName(),
+		filename:    part.FileName(),
+		contentType: part.Header.Get("Content-Type"),
+		sniffedType: http.DetectContentType(head[:n]),
+		size:        int64(n) + rest,
+	})
+}
+
+// finish waits for the parser, once the handler has returned.
+func (s *multipartSummary) finish() {
+	_ = s.pw.Close()
+	<-s.done
+}
+
+func (s *multipartSummary) attrs() []any {
+	// sorted, so that identical requests log identical records
+	names := make([]string, 0, len(s.fields))
+	for name := range s.fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	fields := make([]any, 0, len(names))
+	for _, name := range names {
+		fields = append(fields, slog.Any(name, s.fields[name]))
+	}
+
+	files := make([]map[string]any, 0, len(s.files))
+	for _, file := range s.files {
+		files = append(files, map[string]any{
+			"field":        file.field,
+			"filename":     file.filename,
+			"content_type": file.contentType,
+			"sniffed_type": file.sniffedType,
+			"size":         file.size,
+		})
+	}
+
+	attributes := []any{
+		slog.Group("fields", fields...),
+		slog.Any("files", files),
+	}
+
+	if s.truncated {
+		attributes = append(attributes, slog.Bool("truncated", true))
+	}
+
+	// the handler may not read the whole body
+	if s.err != nil && !errors.Is(s.err, io.EOF) && !errors.Is(s.err, io.ErrUnexpectedEOF) {
+		attributes = append(attributes, slog.String("error", s.err.Error()))
+	}
+
+	return attributes
+}
@@ -0,0 +1,193 @@
+package slogecho
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v5"
+)
+
+type multipartTestRecord struct {
+	Request struct {
+		Multipart struct {
+			Fields    map[string][]string `json:"fields"`
+			Files     []map[string]any    `json:"files"`
+			Truncated bool                `json:"truncated"`
+			Error     string              `json:"error"`
+		} `json:"multipart"`
+	} `json:"request"`
+	Response struct {
+		Status int `json:"status"`
+	} `json:"response"`
+}
+
+// newMultipartBody encodes the fields (in order), then a file of fileSize bytes.
+func newMultipartBody(t *testing.T, fields [][2]string, fileSize int) (*bytes.Buffer, string) {
+	t.Helper()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, field := range fields {
+		if err := w.WriteField(field[0], field[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fileSize > 0 {
+		fw, err := w.CreateFormFile("doc", "a.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write([]byte("\x89PNG\r\n\x1a\n"))
+		_, _ = fw.Write(bytes.Repeat([]byte{0}, fileSize-8))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return &body, w.FormDataContentType()
+}
+
+// serveMultipart serves a multipart request, and returns the record and its raw output.
+func serveMultipart(t *testing.T, handler echo.HandlerFunc, body io.Reader, contentType string) (multipartTestRecord, string) {
+	t.Helper()
+
+	var buf syncBuffer
+
+	config := DefaultConfig()
+	config.WithRequestBody = true
+	config.WithMultipartSummary = true
+
+	e := echo.New()
+	e.Use(NewWithConfig(slog.New(slog.NewJSONHandler(&buf, nil)), config))
+	e.POST("/upload", handler)
+
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the multipart summary blocks the request")
+	}
+
+	var record multipartTestRecord
+	if err := json.Unmarshal([]byte(buf.String()), &record); err != nil {
+		t.Fatal(err)
+	}
+	return record, buf.String()
+}
+
+func TestMultipartSummary(t *testing.T) {
+	body, contentType := newMultipartBody(t, [][2]string{{"name", "bob"}, {"Password", "secret"}, {"name", "alice"}}, 100_000)
+
+	record, output := serveMultipart(t, func(c *echo.Context) error {
+		if _, err := c.MultipartForm(); err != nil {
+			return err
+		}
+		return c.NoContent(204)
+	}, body, contentType)
+
+	fields := record.Request.Multipart.Fields
+	if strings.Join(fields["name"], ",") != "bob,alice" {
+		t.Errorf("expected the name values, got: %v", fields)
+	}
+	if strings.Join(fields["Password"], ",") != "[REDACTED]" || strings.Contains(output, "secret") {
+		t.Errorf("expected the password to be redacted, got: %s", output)
+	}
+
+	files := record.Request.Multipart.Files
+	if len(files) != 1 || files[0]["filename"] != "a.png" || files[0]["size"] != float64(100_000) || files[0]["sniffed_type"] != "image/png" {
+		t.Errorf("expected the file summary, got: %v", files)
+	}
+	if record.Request.Multipart.Truncated || record.Request.Multipart.Error != "" {
+		t.Errorf("unexpected truncation or error: %s", output)
+	}
+}
+
+func TestMultipartSummaryMaxParts(t *testing.T) {
+	fields := [][2]string{}
+	for i := range MultipartMaxParts + 10 {
+		fields = append(fields, [2]string{fmt.Sprintf("field%03d", i), "value"})
+	}
+	body, contentType := newMultipartBody(t, fields, 1024)
+
+	record, output := serveMultipart(t, func(c *echo.Context) error {
+		_, _ = io.Copy(io.Discard, c.Request().Body)
+		return c.NoContent(204)
+	}, body, contentType)
+
+	if !record.Request.Multipart.Truncated {
+		t.Errorf("expected a truncated summary, got: %s", output)
+	}
+	if n := len(record.Request.Multipart.Fields); n != MultipartMaxParts {
+		t.Errorf("expected %d fields, got: %d", MultipartMaxParts, n)
+	}
+	if n := len(record.Request.Multipart.Files); n != 0 {
+		t.Errorf("expected the file after the limit to be skipped, got: %d", n)
+	}
+
+	// fields are sorted
+	i := strings.Index(output, `"field000"`)
+	j := strings.Index(output, `"field001"`)
+	k := strings.Index(output, fmt.Sprintf(`"field%03d"`, MultipartMaxParts-1))
+	if i < 0 || i > j || j > k {
+		t.Errorf("expected sorted fields, got: %s", output)
+	}
+}
+
+func TestMultipartSummaryPartialRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler echo.HandlerFunc
+		fields  int
+	}{
+		{
+			name: "no read",
+			handler: func(c *echo.Context) error {
+				return c.NoContent(413)
+			},
+			fields: 0,
+		},
+		{
+			name: "partial read",
+			handler: func(c *echo.Context) error {
+				// the first field only, the file is never read
+				_, _ = io.ReadFull(c.Request().Body, make([]byte, 200))
+				return c.NoContent(413)
+			},
+			fields: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := newMultipartBody(t, [][2]string{{"name", "bob"}}, 1_000_000)
+
+			record, output := serveMultipart(t, tt.handler, body, contentType)
+
+			if record.Response.Status != 413 {
+				t.Errorf("expected the access record, got: %s", output)
+			}
+			if n := len(record.Request.Multipart.Fields); n != tt.fields {
+				t.Errorf("expected %d fields, got: %s", tt.fields, output)
+			}
+			if record.Request.Multipart.Error != "" {
+				t.Errorf("unexpected error for an unread body: %s", output)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package slogecho
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+)
+
+// StackTraceMaxFrames is the maximum number of frames logged from a stack trace.
+var StackTraceMaxFrames = 32
+
+// packagePrefix is the prefix of the functions of this package, used to trim
+// stack traces at the middleware boundary.
+var packagePrefix = func() string {
+	name := runtime.FuncForPC(reflect.ValueOf(callNext).Pointer()).Name()
+	return name[:strings.LastIndex(name, ".")+1]
+}()
+
+// panicError is the error built from a recovered panic.
+type panicError struct {
+	value any
+	pcs   []uintptr
+}
+
+func newPanicError(value any) *panicError {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+
+	return &panicError{
+		value: value,
+		pcs:   pcs[:n],
+	}
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+func (e *panicError) Unwrap() error {
+	if err, ok := e.value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Callers returns the stack of the goroutine that panicked.
+func (e *panicError) Callers() []uintptr {
+	return e.pcs
+}
+
+// callNext calls the next handler. When recoverPanics is true, panics are
+// converted into 500 errors, except http.ErrAbortHandler.
+func callNext(next echo.HandlerFunc, c *echo.Context, recoverPanics bool) (err error) {
+	if recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				if r == http.ErrAbortHandler { //nolint:errorlint
+					panic(r)
+				}
+
+				err = echo.NewHTTPError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)).Wrap(newPanicError(r))
+			}
+		}()
+	}
+
+	return next(c)
+}
+
+// errorStackTrace returns the deepest stack trace carried by the error tree:
+// pkg/errors-style `StackTrace()` or `Callers() []uintptr`.
+func errorStackTrace(err error) []string {
+	var pcs []uintptr
+
+	walkErrors(err, func(e error) bool {
+		if stack := extractCallers(e); len(stack) > 0 {
+			pcs = stack
+		}
+		return true
+	})
+
+	if len(pcs) == 0 {
+		return nil
+	}
+
+	return formatStack(pcs)
+}
+
+func extractCallers(err error) []uintptr {
+	if c, ok := err.(interface{ Callers() []uintptr }); ok {
+		return c.Callers()
+	}
+
+	// pkg/errors: `StackTrace() errors.StackTrace`, where StackTrace is a slice of Frame (uintptr)
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return nil
+	}
+
+	out := method.Type().Out(0)
+	if out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+
+	frames := method.Call(nil)[0]
+	pcs := make([]uintptr, 0, frames.Len())
+	for i := 0; i < frames.Len(); i++ {
+		pcs = append(pcs, uintptr(frames.Index(i).Uint()))
+	}
+
+	return pcs
+}
+
+// formatStack formats a stack trace as "function file:line" items. Frames of the
+// runtime are removed, and the stack is trimmed at the middleware boundary.
+func formatStack(pcs []uintptr) []string {
+	output := []string{}
+	frames := runtime.CallersFrames(pcs)
+	started := false
+
+	for {
+		frame, more := frames.Next()
+
+		switch {
+		case strings.HasPrefix(frame.Function, "runtime."):
+			// skip
+		case strings.HasPrefix(frame.Function, packagePrefix):
+			if started {
+				return output
+			}
+		default:
+			started = true
+			output = append(output, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+		}
+
+		if !more || len(output) >= StackTraceMaxFrames {
+			return output
+		}
+	}
+}
+
+func findPanicError(err error) (*panicError, bool) {
+	var pe *panicError
+	if errors.As(err, &pe) {
+		return pe, true
+	}
+	return nil, false
+}
@@ -0,0 +1,37 @@
+package slogecho
+
+import (
+	"log/slog"
+	"time"
+)
+
+// SlowRequestThreshold raises the log level of requests lasting at least Latency.
+type SlowRequestThreshold struct {
+	Latency time.Duration
+	Level   slog.Level
+}
+
+// slowRequestThreshold returns the highest threshold reached by the request.
+// Thresholds declared for the route template take precedence over the global ones.
+func (config Config) slowRequestThreshold(route string, latency time.Duration) (SlowRequestThreshold, bool) {
+	thresholds := config.SlowRequestThresholds
+	if routeThresholds, ok := config.RouteSlowRequestThresholds[route]; ok {
+		thresholds = routeThresholds
+	}
+
+	var output SlowRequestThreshold
+	found := false
+
+	for _, threshold := range thresholds {
+		if latency < threshold.Latency {
+			continue
+		}
+
+		if !found || threshold.Latency > output.Latency {
+			output = threshold
+			found = true
+		}
+	}
+
+	return output, found
+}
@@ -0,0 +1,207 @@
+package slogecho
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+)
+
+// BodySpooler creates the destination of a spooled body. kind is "request"
+// or "response". The reference identifies the spooled body in the log
+// record (eg: a file path or an object key).
+type BodySpooler interface {
+	Create(c *echo.Context, kind string) (w io.WriteCloser, ref string, err error)
+}
+
+// BodySpoolerFunc is an adapter to use a function as a BodySpooler.
+type BodySpoolerFunc func(c *echo.Context, kind string) (io.WriteCloser, string, error)
+
+// Create implements BodySpooler.
+func (f BodySpoolerFunc) Create(c *echo.Context, kind string) (io.WriteCloser, string, error) {
+	return f(c, kind)
+}
+
+// DirSpooler spools bodies as files of a directory. The directory must exist,
+// see NewDirSpooler.
+type DirSpooler struct {
+	Dir string
+}
+
+// NewDirSpooler returns a BodySpooler writing files in dir, created if needed.
+func NewDirSpooler(dir string) (*DirSpooler, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &DirSpooler{Dir: dir}, nil
+}
+
+// Create implements BodySpooler.
+func (s *DirSpooler) Create(c *echo.Context, kind string) (io.WriteCloser, string, error) {
+	f, err := os.CreateTemp(s.Dir, kind+"-*.body")
+	if err != nil {
+		return nil, "", err
+	}
+
+	return f, f.Name(), nil
+}
+
+// Cleanup removes the spooled files older than retention. It returns the
+// number of removed files.
+func (s *DirSpooler) Cleanup(retention time.Duration) (int, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return 0, err
+	}
+
+	deadline := time.Now().Add(-retention)
+	removed := 0
+	var errs []error
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".body") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(deadline) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(s.Dir, entry.Name())); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+// OpenSpooledBody returns a reader of the original body, from a body spooled
+// with the given encryption key (may be nil) and compression. Reads fail when
+// an encrypted body has been modified or truncated.
+func OpenSpooledBody(r io.Reader, key []byte, gzipped bool) (io.Reader, error) {
+	if key != nil {
+		decrypter, err := newSpoolDecrypter(r, key)
+		if err != nil {
+			return nil, err
+		}
+
+		r = decrypter
+	}
+
+	if gzipped {
+		return gzip.NewReader(r)
+	}
+
+	return r, nil
+}
+
+// spool writes a body to a BodySpooler, optionally compressed and encrypted
+// (chunked AES-GCM, see spoolEncrypter).
+type spool struct {
+	ref     string
+	w       io.Writer
+	closers []io.Closer // from the outermost writer
+
+	hash      hash.Hash
+	size      int64
+	truncated bool
+	err       error
+}
+
+func (m *Middleware) openSpool(c *echo.Context, kind string) *spool {
+	s := &spool{hash: sha256.New()}
+
+	dst, ref, err := m.config.BodySpooler.Create(c, kind)
+	if err != nil {
+		s.err = err
+		return s
+	}
+
+	s.ref = ref
+	s.w = dst
+	s.closers = []io.Closer{dst}
+
+	if m.config.SpoolEncryptionKey != nil {
+		encrypter, err := newSpoolEncrypter(dst, m.config.SpoolEncryptionKey)
+		if err != nil {
+			s.err = err
+			_ = s.close()
+			return s
+		}
+
+		s.w = encrypter
+		s.closers = append([]io.Closer{encrypter}, s.closers...)
+	}
+
+	if m.config.SpoolGzip {
+		gz := gzip.NewWriter(s.w)
+		s.w = gz
+		s.closers = append([]io.Closer{gz}, s.closers...)
+	}
+
+	return s
+}
+
+func (s *spool) write(b []byte) {
+	if s.err != nil || s.truncated {
+		return
+	}
+
+	if s.size+int64(len(b)) > SpoolBodyMaxSize {
+		b = b[:SpoolBodyMaxSize-s.size]
+		s.truncated = true
+	}
+
+	s.hash.Write(b)
+	s.size += int64(len(b))
+	if _, err := s.w.Write(b); err != nil {
+		s.err = err
+	}
+}
+
+func (s *spool) close() error {
+	for _, closer := range s.closers {
+		if err := closer.Close(); err != nil && s.err == nil {
+			s.err = err
+		}
+	}
+	s.closers = nil
+
+	return s.err
+}
+
+// closeSpools closes the spools of the request and response bodies. It may be
+// called several times.
+func closeSpools(br *bodyReader, bw *bodyWriter) {
+	for _, s := range []*spool{br.spool, bw.spool} {
+		if s != nil {
+			_ = s.close()
+		}
+	}
+}
+
+// attrs returns the attributes logged instead of the body.
+func (s *spool) attrs() []any {
+	attributes := []any{
+		slog.Int64("size", s.size),
+		slog.String("sha256", hex.EncodeToString(s.hash.Sum(nil))),
+		slog.String("ref", s.ref),
+	}
+	if s.truncated {
+		attributes = append(attributes, slog.Bool("truncated", true))
+	}
+	return attributes
+}
@@ -0,0 +1,195 @@
+package slogecho
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Spooled bodies are encrypted with AES-GCM, in chunks, so that they can be
+// written and read as streams:
+//
+//	salt (32 bytes) | chunk header (4 bytes) | sealed chunk | chunk header | sealed chunk ...
+//
+// Each body is encrypted with its own key, derived from SpoolEncryptionKey and
+// a random salt with HKDF-SHA256, so that nonces are never reused across
+// bodies sharing SpoolEncryptionKey. The nonce of a chunk is its index.
+//
+// The chunk header is the size of the sealed chunk, its high bit flags the
+// final chunk. The header is authenticated as additional data, so that
+// modified, reordered or truncated chunks are detected.
+
+const (
+	spoolChunkSize  = 64 * 1024
+	spoolSaltLen    = 32
+	spoolFinalChunk = 1 << 31
+	spoolKeyInfo    = "slog-echo spooled body"
+)
+
+var errSpoolTruncated = errors.New("spooled body is truncated")
+
+// newSpoolAEAD derives the key of a body from its salt. The derived key has
+// the size of the master key (AES-128, AES-192 or AES-256).
+func newSpoolAEAD(key []byte, salt []byte) (cipher.AEAD, error) {
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, err
+	}
+
+	bodyKey, err := hkdf.Key(sha256.New, key, salt, spoolKeyInfo, len(key))
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(bodyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func spoolChunkNonce(aead cipher.AEAD, index uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], index)
+	return nonce
+}
+
+// spoolEncrypter encrypts a body written to w. Close seals the final chunk,
+// it does not close w.
+type spoolEncrypter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	index uint64
+	buf   []byte
+}
+
+func newSpoolEncrypter(w io.Writer, key []byte) (*spoolEncrypter, error) {
+	salt := make([]byte, spoolSaltLen)
+	_, _ = rand.Read(salt)
+
+	aead, err := newSpoolAEAD(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(salt); err != nil {
+		return nil, err
+	}
+
+	return &spoolEncrypter{w: w, aead: aead}, nil
+}
+
+// implements io.Writer
+func (e *spoolEncrypter) Write(b []byte) (int, error) {
+	e.buf = append(e.buf, b...)
+
+	// the last chunk is sealed by Close
+	for len(e.buf) > spoolChunkSize {
+		if err := e.seal(e.buf[:spoolChunkSize], false); err != nil {
+			return 0, err
+		}
+		e.buf = e.buf[spoolChunkSize:]
+	}
+
+	return len(b), nil
+}
+
+// implements io.Closer
+func (e *spoolEncrypter) Close() error {
+	return e.seal(e.buf, true)
+}
+
+func (e *spoolEncrypter) seal(chunk []byte, final bool) error {
+	header := make([]byte, 4)
+	size := uint32(len(chunk) + e.aead.Overhead())
+	if final {
+		size |= spoolFinalChunk
+	}
+	binary.BigEndian.PutUint32(header, size)
+
+	sealed := e.aead.Seal(header, spoolChunkNonce(e.aead, e.index), chunk, header)
+	e.index++
+
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+// spoolDecrypter decrypts a body encrypted by spoolEncrypter.
+type spoolDecrypter struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	index uint64
+	buf   []byte
+	final bool
+}
+
+func newSpoolDecrypter(r io.Reader, key []byte) (*spoolDecrypter, error) {
+	salt := make([]byte, spoolSaltLen)
+	if _, err := io.ReadFull(r, salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := newSpoolAEAD(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &spoolDecrypter{r: r, aead: aead}, nil
+}
+
+// implements io.Reader
+func (d *spoolDecrypter) Read(b []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.final {
+			// data appended after the final chunk
+			if n, _ := d.r.Read(make([]byte, 1)); n > 0 {
+				return 0, errors.New("spooled body has data after the final chunk")
+			}
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(b, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *spoolDecrypter) open() error {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(d.r, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return errSpoolTruncated
+		}
+		return err
+	}
+
+	size := binary.BigEndian.Uint32(header)
+	final := size&spoolFinalChunk != 0
+	size &^= spoolFinalChunk
+	if size < uint32(d.aead.Overhead()) || size > uint32(spoolChunkSize+d.aead.Overhead()) {
+		return errors.New("spooled body has an invalid chunk")
+	}
+
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return errSpoolTruncated
+	}
+
+	chunk, err := d.aead.Open(nil, spoolChunkNonce(d.aead, d.index), sealed, header)
+	if err != nil {
+		return err
+	}
+
+	d.index++
+	d.buf = chunk
+	d.final = final
+	return nil
+}
@@ -0,0 +1,210 @@
+package slogecho
+
+import (
+	"bytes"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+)
+
+var spoolTestKey = bytes.Repeat([]byte{7}, 32)
+
+// spooledFiles returns the contents of the files spooled in dir.
+func spooledFiles(t *testing.T, dir string) [][]byte {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := [][]byte{}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, b)
+	}
+	return files
+}
+
+func readSpooledBody(b []byte) ([]byte, error) {
+	r, err := OpenSpooledBody(bytes.NewReader(b), spoolTestKey, true)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestSpoolEncryption(t *testing.T) {
+	dir := t.TempDir()
+	spooler, err := NewDirSpooler(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.WithRequestBody = true
+	config.BodySpooler = spooler
+	config.SpoolGzip = true
+	config.SpoolEncryptionKey = spoolTestKey
+
+	body := strings.Repeat("0123456789", 30000)
+
+	e := echo.New()
+	e.Use(NewWithConfig(slog.New(slog.NewTextHandler(io.Discard, nil)), config))
+	e.POST("/webhook", func(c *echo.Context) error {
+		_, _ = io.Copy(io.Discard, c.Request().Body)
+		return c.NoContent(204)
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook", strings.NewReader(body)))
+
+	files := spooledFiles(t, dir)
+	if len(files) != 1 {
+		t.Fatalf("spooled files: got %d, want 1", len(files))
+	}
+	spooled := files[0]
+
+	decoded, err := readSpooledBody(spooled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != body {
+		t.Error("spooled body differs from the request body")
+	}
+
+	tampered := bytes.Clone(spooled)
+	tampered[len(tampered)/2] ^= 1
+	if _, err := readSpooledBody(tampered); err == nil {
+		t.Error("expected an error reading a modified body")
+	}
+
+	if _, err := readSpooledBody(spooled[:len(spooled)-1]); err == nil {
+		t.Error("expected an error reading a truncated body")
+	}
+}
+
+func TestSpoolClosedOnPanic(t *testing.T) {
+	dir := t.TempDir()
+	spooler, err := NewDirSpooler(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.WithRequestBody = true
+	config.BodySpooler = spooler
+	config.SpoolGzip = true
+	config.SpoolEncryptionKey = spoolTestKey
+
+	body := strings.Repeat("0123456789", 30000)
+
+	e := echo.New()
+	e.Use(NewWithConfig(slog.New(slog.NewTextHandler(io.Discard, nil)), config))
+	e.POST("/webhook", func(c *echo.Context) error {
+		_, _ = io.Copy(io.Discard, c.Request().Body)
+		panic("boom")
+	})
+
+	func() {
+		defer func() { _ = recover() }()
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook", strings.NewReader(body)))
+	}()
+
+	for _, spooled := range spooledFiles(t, dir) {
+		decoded, err := readSpooledBody(spooled)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(decoded) != body {
+			t.Error("spooled body differs from the request body")
+		}
+	}
+}
+
+func TestSpoolClosedAfterErrorHandler(t *testing.T) {
+	var buf syncBuffer
+
+	dir := t.TempDir()
+	spooler, err := NewDirSpooler(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.WithResponseBody = true
+	config.HandleError = true
+	config.BodySpooler = spooler
+
+	e := echo.New()
+	e.HTTPErrorHandler = func(c *echo.Context, err error) {
+		// appends an error trailer to the partial response
+		_, _ = c.Response().Write([]byte("\nerror: " + err.Error()))
+	}
+	e.Use(NewWithConfig(slog.New(slog.NewTextHandler(&buf, nil)), config))
+	e.GET("/fail", func(c *echo.Context) error {
+		_, _ = c.Response().Write([]byte(strings.Repeat("x", ResponseBodyMaxSize+1)))
+		return echo.ErrInternalServerError
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+
+	if output := buf.String(); strings.Contains(output, "spool_error") || !strings.Contains(output, "response.body_spool.ref=") {
+		t.Errorf("expected a spooled response body, got: %s", output)
+	}
+}
+
+func TestSpoolEncryptionNonces(t *testing.T) {
+	body := bytes.Repeat([]byte("0123456789"), 3*spoolChunkSize/10)
+
+	type keyNonce struct{ key, nonce string }
+	seen := map[keyNonce]int{}
+
+	for i := range 2 {
+		var buf bytes.Buffer
+		encrypter, err := newSpoolEncrypter(&buf, spoolTestKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := encrypter.Write(body); err != nil {
+			t.Fatal(err)
+		}
+		if err := encrypter.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		salt := buf.Bytes()[:spoolSaltLen]
+		key, err := hkdf.Key(sha256.New, spoolTestKey, salt, spoolKeyInfo, len(spoolTestKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for index := range encrypter.index {
+			kn := keyNonce{string(key), string(spoolChunkNonce(encrypter.aead, index))}
+			if j, found := seen[kn]; found {
+				t.Fatalf("bodies %d and %d share the key and nonce of chunk %d", j, i, index)
+			}
+			seen[kn] = i
+		}
+
+		r, err := OpenSpooledBody(bytes.NewReader(buf.Bytes()), spoolTestKey, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded, err := io.ReadAll(r); err != nil || !bytes.Equal(decoded, body) {
+			t.Fatalf("spooled body differs from the body: %v", err)
+		}
+	}
+
+	if len(seen) != 2*3 {
+		t.Errorf("expected 3 chunks per body, got: %d", len(seen))
+	}
+}
@@ -0,0 +1,82 @@
+package slogecho
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+)
+
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// logRequestStart logs the "request started" record, with the request-side
+// attributes only. It returns the correlation id of the record, or an empty
+// string when the request has been filtered out.
+func (m *Middleware) logRequestStart(c *echo.Context, start time.Time, params map[string]string) string {
+	config := m.config
+
+	for _, filter := range config.RequestStartFilters {
+		if !filter(c, nil) {
+			return ""
+		}
+	}
+
+	correlationID := newCorrelationID()
+
+	req := c.Request()
+
+	requestAttributes := []any{
+		slog.Time("time", start.UTC()),
+		slog.String("method", req.Method),
+		slog.String("host", req.Host),
+		slog.String("path", req.URL.Path),
+		slog.String("query", req.URL.RawQuery),
+		slog.Any("params", params),
+		slog.String("route", c.Path()),
+		slog.String("referer", req.Referer()),
+		slog.Int64("content_length", req.ContentLength),
+	}
+
+	if config.WithClientIP {
+		requestAttributes = append(requestAttributes, slog.String("ip", c.RealIP()))
+	}
+
+	if config.WithRequestHeader {
+		kv := []any{}
+
+		for k, v := range req.Header {
+			if _, found := HiddenRequestHeaders[strings.ToLower(k)]; found {
+				continue
+			}
+			kv = append(kv, slog.Any(k, v))
+		}
+
+		requestAttributes = append(requestAttributes, slog.Group("header", kv...))
+	}
+
+	if config.WithUserAgent {
+		requestAttributes = append(requestAttributes, slog.String("user-agent", req.UserAgent()))
+	}
+
+	attributes := []slog.Attr{
+		slog.Group("request", requestAttributes...),
+		slog.String(CorrelationIDKey, correlationID),
+	}
+
+	if requestID := getRequestID(c); config.WithRequestID && requestID != "" {
+		attributes = append(attributes, slog.String(RequestIDKey, requestID))
+	}
+
+	attributes = append(attributes, extractTraceSpanID(req.Context(), config.WithTraceID, config.WithSpanID)...)
+
+	m.logger.LogAttrs(req.Context(), config.RequestStartLevel, "Request started", attributes...)
+
+	return correlationID
+}
@@ -0,0 +1,61 @@
+package slogecho
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// streamProgress periodically logs a record while a streaming response is
+// being written.
+type streamProgress struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	stopped bool
+}
+
+func (m *Middleware) startStreamProgress(r *inflightRequest) *streamProgress {
+	p := &streamProgress{}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.timer = time.AfterFunc(m.config.StreamProgressInterval, func() { m.fireStreamProgress(p, r) })
+	return p
+}
+
+func (m *Middleware) fireStreamProgress(p *streamProgress, r *inflightRequest) {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return
+	}
+	p.timer.Reset(m.config.StreamProgressInterval)
+	p.mu.Unlock()
+
+	attributes := append(
+		m.inflightAttributes(r, time.Now()),
+		slog.Group("stream", streamAttributes(r.bw)...),
+	)
+
+	m.logger.LogAttrs(r.ctx, m.config.StreamProgressLevel, "Stream in progress", attributes...)
+}
+
+func (p *streamProgress) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopped = true
+	p.timer.Stop()
+}
+
+// streamAttributes returns the counters of a streaming response.
+func streamAttributes(bw *bodyWriter) []any {
+	attributes := []any{
+		slog.Int64("flushes", bw.flushes.Load()),
+	}
+	if bw.eventStream {
+		attributes = append(attributes, slog.Int64("events", bw.events.Load()))
+	}
+	return attributes
+}
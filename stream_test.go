@@ -0,0 +1,70 @@
+package slogecho
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v5"
+)
+
+func TestStreamProgressStopsOnPanic(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	config := DefaultConfig()
+	config.StreamProgressInterval = 20 * time.Millisecond
+
+	e := echo.New()
+	e.Use(NewWithConfig(logger, config))
+	e.GET("/events", func(c *echo.Context) error {
+		c.Response().Header().Set("Content-Type", "text/event-stream")
+		_, _ = c.Response().Write([]byte("data: ping\n\n"))
+		time.Sleep(50 * time.Millisecond)
+		panic("boom")
+	})
+
+	serveRecovered(e, "/events")
+
+	records := strings.Count(buf.String(), "Stream in progress")
+	if records == 0 {
+		t.Fatal("expected progress records while streaming")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if after := strings.Count(buf.String(), "Stream in progress"); after != records {
+		t.Errorf("progress records after the panic: got %d, want %d", after, records)
+	}
+}
+
+func TestStreamKeepsSpoolReference(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	config := DefaultConfig()
+	config.WithResponseBody = true
+	spooler, err := NewDirSpooler(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.BodySpooler = spooler
+
+	e := echo.New()
+	e.Use(NewWithConfig(logger, config))
+	e.GET("/export", func(c *echo.Context) error {
+		_, _ = c.Response().Write([]byte(strings.Repeat("x", ResponseBodyMaxSize+1)))
+		http.NewResponseController(c.Response()).Flush()
+		_, _ = c.Response().Write([]byte("tail"))
+		return nil
+	})
+
+	serveRecovered(e, "/export")
+
+	output := buf.String()
+	if !strings.Contains(output, "response.body_spool.ref=") || !strings.Contains(output, "response.body_spool.truncated=true") {
+		t.Errorf("expected a truncated spool reference, got: %s", output)
+	}
+}
@@ -0,0 +1,118 @@
+package slogecho
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v5"
+)
+
+const (
+	timingsCtxKey = "slog-echo.timings"
+)
+
+type timing struct {
+	name     string
+	duration time.Duration
+	count    int
+}
+
+// timings accumulates named durations in the request scope. It is safe for
+// concurrent use, since handlers may spawn goroutines.
+type timings struct {
+	mu      sync.Mutex
+	entries []*timing
+}
+
+func (t *timings) observe(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, entry := range t.entries {
+		if entry.name == name {
+			entry.duration += d
+			entry.count++
+			return
+		}
+	}
+
+	t.entries = append(t.entries, &timing{name: name, duration: d, count: 1})
+}
+
+func (t *timings) attrs() []any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	output := make([]any, 0, len(t.entries))
+	for _, entry := range t.entries {
+		output = append(
+			output,
+			slog.Group(
+				entry.name,
+				slog.Duration("duration", entry.duration),
+				slog.Int("count", entry.count),
+			),
+		)
+	}
+	return output
+}
+
+// serverTiming formats the timings as a W3C Server-Timing header value.
+func (t *timings) serverTiming() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	items := make([]string, 0, len(t.entries))
+	for _, entry := range t.entries {
+		ms := float64(entry.duration) / float64(time.Millisecond)
+		items = append(items, serverTimingName(entry.name)+";dur="+strconv.FormatFloat(ms, 'f', 3, 64))
+	}
+	return strings.Join(items, ", ")
+}
+
+// serverTimingName converts a timing name into a valid http token.
+func serverTimingName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+func getTimings(c *echo.Context) *timings {
+	if t, ok := c.Get(timingsCtxKey).(*timings); ok {
+		return t
+	}
+
+	t := &timings{}
+	c.Set(timingsCtxKey, t)
+	return t
+}
+
+// StartTimer starts a named timer in the request scope, such as "db" or "cache".
+// The returned function stops the timer. Durations and counts of timers sharing
+// the same name are accumulated.
+func StartTimer(c *echo.Context, name string) func() {
+	t := getTimings(c)
+	start := time.Now()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.observe(name, time.Since(start))
+		})
+	}
+}
+
+// Observe adds a duration to a named timer in the request scope.
+func Observe(c *echo.Context, name string, d time.Duration) {
+	getTimings(c).observe(name, d)
+}
@@ -0,0 +1,57 @@
+package slogecho
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// watchdog logs a record when a request is still running after a threshold,
+// then periodically.
+type watchdog struct {
+	mu       sync.Mutex
+	timer    *time.Timer
+	stopped  bool
+	warnings int
+}
+
+func (m *Middleware) startWatchdog(r *inflightRequest) *watchdog {
+	w := &watchdog{}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.timer = time.AfterFunc(m.config.WatchdogThreshold, func() { m.fireWatchdog(w, r) })
+	return w
+}
+
+func (m *Middleware) fireWatchdog(w *watchdog, r *inflightRequest) {
+	w.mu.Lock()
+	if w.stopped {
+		w.mu.Unlock()
+		return
+	}
+	w.warnings++
+	warnings := w.warnings
+	if m.config.WatchdogInterval > 0 {
+		w.timer.Reset(m.config.WatchdogInterval)
+	}
+	w.mu.Unlock()
+
+	attributes := append(
+		m.inflightAttributes(r, time.Now()),
+		slog.Int("watchdog_warnings", warnings),
+	)
+
+	m.logger.LogAttrs(r.ctx, m.config.WatchdogLevel, "Request still running", attributes...)
+}
+
+// stop stops the watchdog and returns the number of warnings fired.
+func (w *watchdog) stop() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stopped = true
+	w.timer.Stop()
+	return w.warnings
+}
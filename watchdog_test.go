@@ -0,0 +1,66 @@
+package slogecho
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v5"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use by the handlers and the timers.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// serveRecovered serves the request, and recovers the panics of the handler.
+func serveRecovered(e *echo.Echo, path string) {
+	defer func() { _ = recover() }()
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+}
+
+func TestWatchdogStopsOnPanic(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	config := DefaultConfig()
+	config.WatchdogThreshold = 20 * time.Millisecond
+	config.WatchdogInterval = 20 * time.Millisecond
+
+	e := echo.New()
+	e.Use(NewWithConfig(logger, config))
+	e.GET("/panic", func(c *echo.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		panic("boom")
+	})
+
+	serveRecovered(e, "/panic")
+
+	warnings := strings.Count(buf.String(), "Request still running")
+	if warnings == 0 {
+		t.Fatal("expected watchdog records while the handler is running")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if after := strings.Count(buf.String(), "Request still running"); after != warnings {
+		t.Errorf("watchdog records after the panic: got %d, want %d", after, warnings)
+	}
+}